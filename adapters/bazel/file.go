@@ -106,6 +106,31 @@ Stmt:
 	}
 }
 
+// AddRule appends the rule even when a rule with the same name, or no name at
+// all such as a subinclude, already exists.
+func (this *File) AddRule(rule please.Rule) {
+	switch rule := rule.(type) {
+	case *Rule:
+		this.Stmt = append(this.Stmt, rule.Call)
+	default:
+		panic(fmt.Errorf("rule not created by package"))
+	}
+}
+
+// NumOtherStmts returns the number of top level statements which are not
+// function calls such as assignments, conditionals and comment blocks.
+func (this *File) NumOtherStmts() int {
+	var n int
+
+	for _, stmt := range this.Stmt {
+		if _, ok := stmt.(*build.CallExpr); !ok {
+			n++
+		}
+	}
+
+	return n
+}
+
 func (this *File) DelRule(name string) bool {
 	rule := this.GetRule(name)
 	if rule == nil {
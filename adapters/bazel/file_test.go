@@ -26,10 +26,18 @@ func TestFile_SetRule(t *testing.T) {
 	NewBuilderSuite(t).TestFile_SetRule()
 }
 
+func TestFile_AddRule(t *testing.T) {
+	NewBuilderSuite(t).TestFile_AddRule()
+}
+
 func TestFile_DelRule(t *testing.T) {
 	NewBuilderSuite(t).TestFile_DelRule()
 }
 
+func TestFile_NumOtherStmts(t *testing.T) {
+	NewBuilderSuite(t).TestFile_NumOtherStmts()
+}
+
 func (t *BuilderSuite) TestFile_GetPath() {
 	type T = BuilderSuite
 
@@ -157,6 +165,38 @@ func (t *BuilderSuite) TestFile_SetRule() {
 	})
 }
 
+func (t *BuilderSuite) TestFile_AddRule() {
+	type T = BuilderSuite
+
+	t.It("appends rule even when a rule with the same name exists", func(t *T) {
+		file, err := t.builder.Parse("BUILD.plz", buildtools)
+		require.NoError(t, err)
+		require.IsType(t, &bazel.File{}, file)
+
+		have := (file.(*bazel.File)).Unwrap()
+
+		call := &build.CallExpr{
+			X: &build.Ident{Name: "package"},
+			List: []build.Expr{
+				&build.AssignExpr{
+					LHS: &build.Ident{Name: "default_visibility"},
+					Op:  "=",
+					RHS: &build.ListExpr{
+						List: []build.Expr{&build.StringExpr{Value: "//app/..."}},
+					},
+				},
+			},
+		}
+
+		file.AddRule(bazel.NewRule(call))
+
+		want := t.Buildtools()
+		want.Stmt = append(want.Stmt, call)
+
+		require.Equal(t, want, have)
+	})
+}
+
 func (t *BuilderSuite) TestFile_DelRule() {
 	type T = BuilderSuite
 
@@ -175,3 +215,17 @@ func (t *BuilderSuite) TestFile_DelRule() {
 		require.Equal(t, want, have)
 	})
 }
+
+func (t *BuilderSuite) TestFile_NumOtherStmts() {
+	type T = BuilderSuite
+
+	t.It("counts top level statements which are not calls", func(t *T) {
+		file, err := t.builder.Parse("BUILD.plz", buildtools)
+		require.NoError(t, err)
+		require.Equal(t, 0, file.NumOtherStmts())
+
+		file, err = t.builder.Parse("BUILD.plz", fstring)
+		require.NoError(t, err)
+		require.Equal(t, 2, file.NumOtherStmts())
+	})
+}
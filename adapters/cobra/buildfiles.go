@@ -0,0 +1,12 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func BuildfilesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "buildfiles",
+		Short: "build file maintenance",
+	}
+}
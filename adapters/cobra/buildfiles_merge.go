@@ -0,0 +1,40 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func BuildfilesMergeCmd(app ctl.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge [path...]",
+		Short: "merge ambiguous build files",
+		Long: Description(`
+			Merges every directory which contains more than one build file, such as both
+			a BUILD and a BUILD.plz file, into a single build file. The merged build file
+			uses the first build file name configured by please and the others are
+			removed. Rules defined in several build files are combined, although a
+			directory is left untouched when its build files define the same rule with a
+			different kind or conflicting attributes. Everything which could not be
+			merged is reported and the command fails.
+		`),
+		Example: Long(`
+			Merge all ambiguous build files under the routes directory.
+			    $ wollemi buildfiles merge project/service/routes/...
+
+			Merge all ambiguous build files under the working directory.
+			    $ wollemi buildfiles merge
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.BuildfilesMerge(args)
+		},
+	}
+
+	return cmd
+}
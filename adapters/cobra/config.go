@@ -0,0 +1,12 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func ConfigCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "config inspection",
+	}
+}
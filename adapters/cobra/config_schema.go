@@ -0,0 +1,39 @@
+package cobra
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func ConfigSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "print the config file json schema",
+		Long: Description(`
+			Prints the JSON Schema which describes a .wollemi.json config file. Editors
+			can use the schema to validate and complete config files by referencing it
+			from the "$schema" key of a config file.
+		`),
+		Example: Long(`
+			Write the config json schema to a file.
+			    $ wollemi config schema > wollemi.schema.json
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			buf, err := json.MarshalIndent(wollemi.Schema(), "", "  ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), string(buf))
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,58 @@
+package cobra
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func ConfigShowCmd(app ctl.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [path]",
+		Short: "show the merged config for a directory",
+		Long: Description(`
+			Shows the config which applies to a directory as json. The config is the
+			result of merging together every .wollemi.json config file discovered
+			between the project root and the directory. Each field is annotated with
+			the config file which set it, or "default" when no config file sets it.
+		`),
+		Example: Long(`
+			Show the config which applies to the routes directory.
+			    $ wollemi config show project/service/routes
+
+			Show the config which applies to the working directory.
+			    $ wollemi config show
+		`),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			config, err := wollemi.ConfigShow(path)
+			if err != nil {
+				return err
+			}
+
+			buf, err := json.MarshalIndent(config, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), string(buf))
+
+			return nil
+		},
+	}
+
+	return cmd
+}
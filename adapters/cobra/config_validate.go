@@ -0,0 +1,37 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func ConfigValidateCmd(app ctl.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path...]",
+		Short: "validate config files",
+		Long: Description(`
+			Validates every .wollemi.json config file found under the provided paths.
+			Config files are decoded strictly, meaning unknown keys and values of the
+			wrong type are reported along with the file, line and key at fault. The
+			command fails when any config file is invalid.
+		`),
+		Example: Long(`
+			Validate all config files under the routes directory.
+			    $ wollemi config validate project/service/routes/...
+
+			Validate all config files under the working directory.
+			    $ wollemi config validate
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.ConfigValidate(args)
+		},
+	}
+
+	return cmd
+}
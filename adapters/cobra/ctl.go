@@ -12,28 +12,50 @@ import (
 
 func Ctl(app ctl.Application) *cobra.Command {
 	var (
-		fmt            = FmtCmd(app)
-		gofmt          = GoFmtCmd(app)
-		root           = RootCmd(app)
-		symlink        = SymlinkCmd()
-		symlinkGoPath  = SymlinkGoPathCmd(app)
-		symlinkList    = SymlinkListCmd(app)
-		rules          = RulesCmd()
-		rulesUnused    = RulesUnusedCmd(app)
-		completion     = CompletionCmd()
-		completionBash = CompletionBashCmd(root)
-		completionZsh  = CompletionZshCmd(root)
+		buildfiles        = BuildfilesCmd()
+		buildfilesMerge   = BuildfilesMergeCmd(app)
+		config            = ConfigCmd()
+		configSchema      = ConfigSchemaCmd()
+		configShow        = ConfigShowCmd(app)
+		configValidate    = ConfigValidateCmd(app)
+		fmt               = FmtCmd(app)
+		gofmt             = GoFmtCmd(app)
+		root              = RootCmd(app)
+		symlink           = SymlinkCmd()
+		symlinkGoPath     = SymlinkGoPathCmd(app)
+		symlinkList       = SymlinkListCmd(app)
+		thirdparty        = ThirdpartyCmd()
+		thirdpartyUnused  = ThirdpartyUnusedCmd(app)
+		thirdpartyCheck   = ThirdpartyCheckCmd(app)
+		thirdpartyUpgrade = ThirdpartyUpgradeCmd(app)
+		rules             = RulesCmd()
+		rulesUnused       = RulesUnusedCmd(app)
+		rulesVisibility   = RulesVisibilityCmd(app)
+		completion        = CompletionCmd()
+		completionBash    = CompletionBashCmd(root)
+		completionZsh     = CompletionZshCmd(root)
 	)
 
 	cmds := []*cobra.Command{
+		buildfiles,
+		buildfilesMerge,
+		config,
+		configSchema,
+		configShow,
+		configValidate,
 		fmt,
 		gofmt,
 		root,
 		symlink,
 		symlinkGoPath,
 		symlinkList,
+		thirdparty,
+		thirdpartyUnused,
+		thirdpartyCheck,
+		thirdpartyUpgrade,
 		rules,
 		rulesUnused,
+		rulesVisibility,
 		completion,
 		completionBash,
 		completionZsh,
@@ -47,10 +69,13 @@ func Ctl(app ctl.Application) *cobra.Command {
 		}
 	}
 
-	addCommands(rules, rulesUnused)
+	addCommands(buildfiles, buildfilesMerge)
+	addCommands(config, configSchema, configShow, configValidate)
+	addCommands(rules, rulesUnused, rulesVisibility)
 	addCommands(symlink, symlinkGoPath, symlinkList)
+	addCommands(thirdparty, thirdpartyUnused, thirdpartyCheck, thirdpartyUpgrade)
 	addCommands(completion, completionBash, completionZsh)
-	addCommands(root, fmt, gofmt, symlink, rules, completion)
+	addCommands(root, buildfiles, config, fmt, gofmt, symlink, rules, thirdparty, completion)
 
 	return root
 }
@@ -1,6 +1,8 @@
 package cobra
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/tcncloud/wollemi/ports/ctl"
@@ -14,6 +16,8 @@ func FmtCmd(app ctl.Application) *cobra.Command {
 		},
 	}
 
+	var sets []string
+
 	cmd := &cobra.Command{
 		Use:   "fmt [path...]",
 		Short: "format build files",
@@ -43,9 +47,15 @@ func FmtCmd(app ctl.Application) *cobra.Command {
 				return err
 			}
 
+			if err := applyConfigOverrides(&config, os.Environ(), sets); err != nil {
+				return err
+			}
+
 			return wollemi.Format(config, args)
 		},
 	}
 
+	addSetFlag(cmd, &sets)
+
 	return cmd
 }
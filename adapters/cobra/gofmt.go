@@ -1,6 +1,8 @@
 package cobra
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/tcncloud/wollemi/ports/ctl"
@@ -13,6 +15,8 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 	create := config.Gofmt.GetCreate()
 	manage := config.Gofmt.GetManage()
 	mapped := map[string]string(nil)
+	sets := []string(nil)
+	fix := false
 
 	cmd := &cobra.Command{
 		Use:   "gofmt [path...]",
@@ -65,6 +69,15 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 			The keep comment can also be placed above go build rules you don't want gofmt
 			to modify. These cases should be rare and this feature should be used only when
 			absolutely necessary.
+
+			When a third party import cannot be resolved gofmt looks up its module and
+			version in the go.sum of the repo and the local module cache, without any
+			network access, and suggests a go_module rule for it. With --fix the rule is
+			written to the build file of the module under the third party dir instead
+			and the import is resolved to it. Packages are only added to existing
+			go_module rules when gofmt.install_missing is also set. Rules are only
+			suggested when the directory of the module is ignored or has a build file
+			which was not parsed.
 		`),
 		Example: Long(`
 			Go format a specific build file.
@@ -75,6 +88,15 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 
 			Recursively go format all build files under the working directory.
 			    $ wollemi gofmt
+
+			Go format without rewriting rules in the routes directory.
+			    $ wollemi gofmt --set gofmt.rewrite=false project/service/routes/...
+
+			Go format and write go_module rules for unresolved third party imports.
+			    $ wollemi gofmt --fix project/service/routes/...
+
+			Go format using a known dependency from the environment.
+			    $ WOLLEMI_KNOWN_DEPENDENCY=github.com/olivere/elastic=//third_party/go:elastic wollemi gofmt
 		`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			wollemi, err := app.Wollemi()
@@ -82,6 +104,10 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 				return err
 			}
 
+			if err := applyConfigOverrides(&config, os.Environ(), sets); err != nil {
+				return err
+			}
+
 			if cmd.Flags().Changed("create") {
 				config.Gofmt.Create = create
 			}
@@ -94,6 +120,10 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 				config.Gofmt.Mapped = mapped
 			}
 
+			if cmd.Flags().Changed("fix") {
+				config.Gofmt.FixUnresolved = &fix
+			}
+
 			return wollemi.GoFormat(config, args)
 		},
 	}
@@ -101,6 +131,9 @@ func GoFmtCmd(app ctl.Application) *cobra.Command {
 	cmd.Flags().StringSliceVar(&create, "create", create, "rule kinds to be created when not found")
 	cmd.Flags().StringSliceVar(&manage, "manage", manage, "rule kinds to be managed")
 	cmd.Flags().StringToStringVar(&mapped, "mapped", nil, "rule kinds to be mapped")
+	cmd.Flags().BoolVar(&fix, "fix", fix, "write go_module rules for unresolved third party imports")
+
+	addSetFlag(cmd, &sets)
 
 	return cmd
 }
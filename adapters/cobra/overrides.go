@@ -0,0 +1,50 @@
+package cobra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+// addSetFlag adds the repeatable --set flag to the command. The values
+// collected are applied by applyConfigOverrides.
+func addSetFlag(cmd *cobra.Command, sets *[]string) {
+	cmd.Flags().StringArrayVar(sets, "set", nil, "override config key with value (key=value)")
+}
+
+// applyConfigOverrides applies the config keys overridden by WOLLEMI_ prefixed
+// environment variables followed by those overridden by --set flags. Unknown
+// environment variables are ignored.
+func applyConfigOverrides(config *wollemi.Config, environ, sets []string) error {
+	for _, env := range environ {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		key, ok := wollemi.EnvKey(kv[0])
+		if !ok {
+			continue
+		}
+
+		if err := config.Set(key, kv[1]); err != nil {
+			return fmt.Errorf("could not apply %s: %v", kv[0], err)
+		}
+	}
+
+	for _, set := range sets {
+		kv := strings.SplitN(set, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("could not apply --set %q: expected key=value", set)
+		}
+
+		if err := config.Set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("could not apply --set: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -8,9 +8,12 @@ import (
 
 func RulesUnusedCmd(app ctl.Application) *cobra.Command {
 	var (
-		prune   bool
-		exclude []string
-		kinds   []string
+		prune      bool
+		transitive bool
+		offline    bool
+		roots      []string
+		exclude    []string
+		kinds      []string
 	)
 
 	cmd := &cobra.Command{
@@ -20,6 +23,20 @@ func RulesUnusedCmd(app ctl.Application) *cobra.Command {
 			Lists potentially unused build rules. Unused in this context simply means no
 			other build files depend on this rule. User discretion is needed to make the
 			final call whether an unused build rule listed here should be pruned.
+
+			With --transitive rules whose dependents are all unused themselves are also
+			listed, along with those dependents, so whole chains of unused rules can be
+			pruned at once.
+
+			With --roots unused instead means not reachable from any of the root rules,
+			which are given as rule kinds or labels. Rules only depended on by other
+			unreachable rules, including binaries and tests which are not roots, are
+			listed as well.
+
+			With --offline the build graph is derived from the deps, srcs, data and tools
+			of the parsed build files rather than queried from please, so neither a plz
+			binary nor a repo which please can fully parse is needed. Rules created by
+			build definitions are missing from such a graph.
 		`),
 		Example: Long(`
 			List all unused go_get rules.
@@ -33,6 +50,15 @@ func RulesUnusedCmd(app ctl.Application) *cobra.Command {
 
 			Prune unused third_party go_get rules.
 			    $ wollemi rules unused --prune --kind go_get third_party/go/...
+
+			Prune third_party go_get rules only depended on by other unused rules.
+			    $ wollemi rules unused --prune --transitive --kind go_get third_party/go/...
+
+			List all rules not reachable from binaries, tests or the k8s deployments.
+			    $ wollemi rules unused --roots go_binary,go_test,//k8s/...
+
+			List all unused rules without querying please for the build graph.
+			    $ wollemi rules unused --offline
 		`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			wollemi, err := app.Wollemi()
@@ -40,11 +66,14 @@ func RulesUnusedCmd(app ctl.Application) *cobra.Command {
 				return err
 			}
 
-			return wollemi.RulesUnused(prune, kinds, args, exclude)
+			return wollemi.RulesUnused(prune, transitive, offline, roots, kinds, args, exclude)
 		},
 	}
 
 	cmd.Flags().BoolVar(&prune, "prune", false, "prune matched rules")
+	cmd.Flags().BoolVar(&transitive, "transitive", false, "include rules only used by unused rules")
+	cmd.Flags().BoolVar(&offline, "offline", false, "derive the build graph from build files instead of please")
+	cmd.Flags().StringSliceVar(&roots, "roots", nil, "rule kinds or labels to find reachable rules from (comma separated)")
 	cmd.Flags().StringSliceVar(&kinds, "kind", kinds, "rule kinds to include (comma separated)")
 	cmd.Flags().StringSliceVar(&exclude, "exclude", nil, "path prefixes to exclude (comma separated)")
 
@@ -0,0 +1,49 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func RulesVisibilityCmd(app ctl.Application) *cobra.Command {
+	var (
+		tighten bool
+		kinds   []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "visibility [path...]",
+		Short: "lists build rules whose visibility can be tightened",
+		Long: Description(`
+			Lists build rules whose visibility is wider than needed by the packages which
+			currently depend on them. The minimal visibility collapses dependent packages
+			into one //dir/... visibility per top level directory. Only rules with an
+			explicit visibility are considered and visibility is never widened. User
+			discretion is needed since future dependents are not accounted for.
+		`),
+		Example: Long(`
+			List all go_library rules whose visibility can be tightened.
+			    $ wollemi rules visibility
+
+			List all go_library and go_binary rules under the routes directory.
+			    $ wollemi rules visibility --kind go_library,go_binary project/service/routes/...
+
+			Tighten the visibility of go_library rules under the routes directory.
+			    $ wollemi rules visibility --tighten project/service/routes/...
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.RulesVisibility(tighten, kinds, args)
+		},
+	}
+
+	cmd.Flags().BoolVar(&tighten, "tighten", false, "rewrite visibility of matched rules")
+	cmd.Flags().StringSliceVar(&kinds, "kind", kinds, "rule kinds to include (comma separated)")
+
+	return cmd
+}
@@ -0,0 +1,12 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func ThirdpartyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "thirdparty",
+		Short: "third party rule maintenance",
+	}
+}
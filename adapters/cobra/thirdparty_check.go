@@ -0,0 +1,41 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func ThirdpartyCheckCmd(app ctl.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check [path...]",
+		Short: "reports duplicate and conflicting third party rules",
+		Long: Description(`
+			Reports go_module, go_get and go_get_with_sources rules which provide the same
+			go module. Rules of the same module at different semantic versions are reported
+			as conflicts and otherwise as duplicates. Rules whose module is also installed
+			by the wildcard of a broader go_get rule, or named by the install list of a
+			broader go_module rule, are reported as well. Every report suggests a canonical
+			target, preferring the greatest version, a go_module rule and the rule in the
+			package named after the module in that order. Without any paths the rules of
+			the configured third party directories are checked.
+		`),
+		Example: Long(`
+			Check all third party rules.
+			    $ wollemi thirdparty check
+
+			Check the third party rules under third_party/go/github.com.
+			    $ wollemi thirdparty check third_party/go/github.com/...
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.ThirdpartyCheck(args)
+		},
+	}
+
+	return cmd
+}
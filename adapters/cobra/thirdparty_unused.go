@@ -0,0 +1,44 @@
+package cobra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func ThirdpartyUnusedCmd(app ctl.Application) *cobra.Command {
+	var prune bool
+
+	cmd := &cobra.Command{
+		Use:   "unused [path...]",
+		Short: "lists unused go_module install entries",
+		Long: Description(`
+			Lists the install entries of go_module rules which install no package imported
+			by the go source files of the repo. Without any paths the go_module rules of
+			the configured third party directories are checked. Modules which third party
+			rules used by first party rules, directly or transitively, depend on are
+			skipped since the packages those rules need can not be known without their
+			sources. A go_module rule none of whose installed
+			packages are imported is reported but never pruned, see rules unused instead.
+		`),
+		Example: Long(`
+			List all unused install entries.
+			    $ wollemi thirdparty unused
+
+			Prune unused install entries under third_party/go/github.com.
+			    $ wollemi thirdparty unused --prune third_party/go/github.com/...
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.ThirdpartyUnused(prune, args)
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "prune unused install entries")
+
+	return cmd
+}
@@ -0,0 +1,47 @@
+package cobra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tcncloud/wollemi/ports/ctl"
+)
+
+func ThirdpartyUpgradeCmd(app ctl.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade <module>@<version>",
+		Short: "upgrades every third party rule of a go module",
+		Long: Description(`
+			Sets the version of every go_module, go_get and go_get_with_sources rule of the
+			go module found under the configured third party directories. The version
+			attribute of go_module rules and the revision attribute of go_get rules are
+			rewritten. When the go.mod of the repo requires the module its requirement is
+			upgraded too, along with the go.sum hashes of the new version which are read
+			from the local module cache. When the cache does not have the version go.mod
+			is left unchanged and go mod download must be run first. The targets depending
+			on each upgraded rule are reported.
+		`),
+		Example: Long(`
+			Upgrade every rule of the cobra module.
+			    $ wollemi thirdparty upgrade github.com/spf13/cobra@v1.3.0
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			at := strings.LastIndex(args[0], "@")
+			if at < 0 {
+				return fmt.Errorf("expected <module>@<version>: %s", args[0])
+			}
+
+			wollemi, err := app.Wollemi()
+			if err != nil {
+				return err
+			}
+
+			return wollemi.ThirdpartyUpgrade(args[0][:at], args[0][at+1:])
+		},
+	}
+
+	return cmd
+}
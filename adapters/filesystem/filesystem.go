@@ -2,7 +2,6 @@ package filesystem
 
 import (
 	"bytes"
-	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,12 +15,15 @@ import (
 func NewFilesystem(log logging.Logger) *Filesystem {
 	return &Filesystem{
 		configs: make(map[string]wollemi.Config),
+		files:   make(map[string]*wollemi.ConfigFile),
 		log:     log,
 	}
 }
 
 type Filesystem struct {
+	base    *wollemi.ConfigFile
 	configs map[string]wollemi.Config
+	files   map[string]*wollemi.ConfigFile
 	log     logging.Logger
 	mu      sync.Mutex
 }
@@ -70,6 +72,8 @@ func (*Filesystem) Walk(root string, walkFn filepath.WalkFunc) error {
 }
 
 func (this *Filesystem) Config(path string) wollemi.Config {
+	files := this.ConfigFiles(path)
+
 	this.mu.Lock()
 	defer this.mu.Unlock()
 
@@ -79,46 +83,126 @@ func (this *Filesystem) Config(path string) wollemi.Config {
 	}
 
 	config = wollemi.Config{}
-	dirs := strings.Split(path, "/")
 
-	var buf bytes.Buffer
+	for _, file := range files {
+		config = config.Merge(file.Config)
+	}
 
-	for i := 0; i < len(dirs)+1; i++ {
-		path := filepath.Join(dirs[:i]...)
+	this.configs[path] = config
 
-		if tmp, ok := this.configs[path]; ok {
-			config = config.Merge(tmp)
-			continue
-		}
+	return config
+}
 
-		log := this.log.WithField("path", path).
-			WithField("file", ".wollemi.json")
+// SetBaseConfig sets the config which every config file found on disk is
+// merged on top of.
+func (this *Filesystem) SetBaseConfig(file wollemi.ConfigFile) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.base = &file
+	this.configs = make(map[string]wollemi.Config)
+}
 
-		err := this.ReadAll(&buf, filepath.Join(path, ".wollemi.json"))
-		if err == nil {
-			tmp := wollemi.Config{}
-			if err := json.Unmarshal(buf.Bytes(), &tmp); err != nil {
-				log.WithError(err).Warn("could not unmarshal json")
+// ConfigFiles returns the base config followed by every config file found
+// between the project root and the provided directory ordered from the project
+// root down. The config files extended by a config file precede it.
+func (this *Filesystem) ConfigFiles(path string) []wollemi.ConfigFile {
+	this.mu.Lock()
+	defer this.mu.Unlock()
 
-				continue
-			}
+	dirs := []string{"."}
 
-			config = config.Merge(tmp)
+	if path != "." && path != "" {
+		chunks := strings.Split(path, "/")
 
-			this.configs[path] = tmp
+		for i := range chunks {
+			dirs = append(dirs, filepath.Join(chunks[:i+1]...))
 		}
+	}
+
+	var files []wollemi.ConfigFile
+
+	if this.base != nil {
+		files = append(files, *this.base)
+	}
+
+	for _, dir := range dirs {
+		files = this.appendConfigFile(files, dir, filepath.Join(dir, ".wollemi.json"), nil)
+	}
+
+	return files
+}
+
+// appendConfigFile appends the config files extended by the config file at
+// path followed by the config file itself, each recorded as applying from dir.
+// The extending stack contains the config files which led to this one and is
+// used to detect cycles.
+func (this *Filesystem) appendConfigFile(files []wollemi.ConfigFile, dir, path string, extending []string) []wollemi.ConfigFile {
+	file := this.configFile(path, len(extending) > 0)
+	if file == nil {
+		return files
+	}
+
+	extending = append(extending[:len(extending):len(extending)], path)
+
+	for _, extend := range file.Config.Extends {
+		extend = filepath.Clean(extend)
+
+		if inStrings(extending, extend) {
+			this.log.WithField("file", path).
+				WithField("extends", extend).
+				WithField("cycle", append(extending, extend)).
+				Warn("config extends cycle")
 
-		if os.IsNotExist(err) {
-			this.configs[path] = config
 			continue
 		}
 
-		if err != nil {
-			log.WithError(err).Warn("could not read file")
+		files = this.appendConfigFile(files, dir, extend, extending)
+	}
+
+	out := *file
+	out.Dir = dir
+
+	return append(files, out)
+}
+
+// configFile reads and caches the config file at path. Missing config files
+// are only reported when required.
+func (this *Filesystem) configFile(path string, required bool) *wollemi.ConfigFile {
+	if file, ok := this.files[path]; ok {
+		return file
+	}
+
+	log := this.log.WithField("file", path)
+
+	var file *wollemi.ConfigFile
+	var buf bytes.Buffer
+
+	err := this.ReadAll(&buf, path)
+	if err == nil {
+		config := wollemi.Config{}
+		if err := wollemi.Unmarshal(buf.Bytes(), &config); err != nil {
+			log.WithError(err).Warn("invalid config")
+		} else {
+			file = &wollemi.ConfigFile{Path: path, Config: config}
 		}
+	} else if !os.IsNotExist(err) || required {
+		log.WithError(err).Warn("could not read file")
 	}
 
-	return config
+	this.files[path] = file
+
+	return file
+}
+
+func inStrings(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (*Filesystem) Readlink(name string) (string, error) {
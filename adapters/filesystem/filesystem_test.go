@@ -0,0 +1,145 @@
+package filesystem_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/adapters/filesystem"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+	"github.com/tcncloud/wollemi/testdata/mem"
+)
+
+func TestFilesystem_ConfigFiles(t *testing.T) {
+	t.Run("orders extended config files before the config file extending them", func(t *testing.T) {
+		chdirTemp(t, map[string]string{
+			".wollemi.json":     `{"extends": ["shared/base.json"]}`,
+			"app/.wollemi.json": `{"extends": ["shared/a.json", "shared/b.json"], "explicit_sources": true}`,
+			"shared/base.json":  `{"default_visibility": "//base/..."}`,
+			"shared/a.json":     `{"extends": ["shared/base.json"], "default_visibility": "//a/..."}`,
+			"shared/b.json":     `{"known_dependency": {"github.com/spf13/cobra": "//third_party/go:cobra"}}`,
+			"app/shared/a.json": `{"default_visibility": "//app/shared/..."}`,
+			"app/shared/b.json": `{"default_visibility": "//app/shared/..."}`,
+		})
+
+		log := mem.NewLogger()
+		fs := filesystem.NewFilesystem(log)
+
+		assert.Equal(t, []string{
+			"shared/base.json",
+			".wollemi.json",
+			"shared/base.json",
+			"shared/a.json",
+			"shared/b.json",
+			"app/.wollemi.json",
+		}, configPaths(fs.ConfigFiles("app")))
+
+		var dirs []string
+		for _, file := range fs.ConfigFiles("app") {
+			dirs = append(dirs, file.Dir)
+		}
+
+		assert.Equal(t, []string{".", ".", "app", "app", "app", "app"}, dirs)
+
+		config := fs.Config("app")
+
+		assert.Equal(t, "//a/...", config.DefaultVisibility)
+		assert.Equal(t, "//third_party/go:cobra", config.KnownDependency["github.com/spf13/cobra"])
+		assert.True(t, config.ExplicitSources.IsTrue())
+		assert.Empty(t, log.Lines())
+	})
+
+	t.Run("resolves extended config files relative to the project root", func(t *testing.T) {
+		chdirTemp(t, map[string]string{
+			"app/server/.wollemi.json":    `{"extends": ["./shared//../shared/base.json", "missing.json"]}`,
+			"shared/base.json":            `{"default_visibility": "//base/..."}`,
+			"app/server/shared/base.json": `{"default_visibility": "//app/server/shared/..."}`,
+		})
+
+		log := mem.NewLogger()
+		fs := filesystem.NewFilesystem(log)
+
+		assert.Equal(t, []string{
+			"shared/base.json",
+			"app/server/.wollemi.json",
+		}, configPaths(fs.ConfigFiles("app/server")))
+
+		assert.Equal(t, "//base/...", fs.Config("app/server").DefaultVisibility)
+
+		var warned []interface{}
+		for _, line := range log.Lines() {
+			if line["msg"] == "could not read file" {
+				warned = append(warned, line["file"])
+			}
+		}
+
+		assert.Equal(t, []interface{}{"missing.json"}, warned)
+	})
+
+	t.Run("reports and ignores extends cycles", func(t *testing.T) {
+		chdirTemp(t, map[string]string{
+			".wollemi.json": `{"extends": ["shared/a.json"]}`,
+			"shared/a.json": `{"extends": ["shared/b.json"], "default_visibility": "//a/..."}`,
+			"shared/b.json": `{"extends": ["shared/a.json"], "default_visibility": "//b/..."}`,
+		})
+
+		log := mem.NewLogger()
+		fs := filesystem.NewFilesystem(log)
+
+		assert.Equal(t, []string{
+			"shared/b.json",
+			"shared/a.json",
+			".wollemi.json",
+		}, configPaths(fs.ConfigFiles(".")))
+
+		var cycles []interface{}
+		for _, line := range log.Lines() {
+			if line["msg"] == "config extends cycle" {
+				cycles = append(cycles, line["cycle"])
+			}
+		}
+
+		assert.Equal(t, []interface{}{
+			[]string{".wollemi.json", "shared/a.json", "shared/b.json", "shared/a.json"},
+		}, cycles)
+
+		assert.Equal(t, "//a/...", fs.Config(".").DefaultVisibility)
+	})
+}
+
+// chdirTemp changes the working directory to a temporary directory holding
+// the provided files until the test completes.
+func chdirTemp(t *testing.T, files map[string]string) {
+	tmp, err := ioutil.TempDir("", "filesystem_test")
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+		os.RemoveAll(tmp)
+	})
+
+	for path, data := range files {
+		path = filepath.Join(tmp, path)
+
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.FileMode(0755)))
+		require.NoError(t, ioutil.WriteFile(path, []byte(data), os.FileMode(0644)))
+	}
+
+	require.NoError(t, os.Chdir(tmp))
+}
+
+func configPaths(files []wollemi.ConfigFile) []string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	return paths
+}
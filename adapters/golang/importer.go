@@ -40,6 +40,20 @@ func (this *Importer) GOPATH() string {
 	return build.Default.GOPATH
 }
 
+// GOMODCACHE returns the directory of the go module cache, which defaults to
+// pkg/mod under the first GOPATH entry as it does for the go command.
+func (this *Importer) GOMODCACHE() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+
+	if gopath := filepath.SplitList(build.Default.GOPATH); len(gopath) > 0 {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+
+	return ""
+}
+
 func (this *Importer) GOROOT() string {
 	return build.Default.GOROOT
 }
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 
 	"github.com/please-build/gcfg"
@@ -20,8 +22,27 @@ func NewCtl() *Ctl {
 
 type Ctl struct{}
 
+// Config reads the please config at path followed by the architecture and
+// local overlays of the same config which exist alongside it.
 func (*Ctl) Config(path string) (config please.Config, err error) {
-	return config, gcfg.FatalOnly(gcfg.ReadFileInto(&config, path))
+	if err := gcfg.FatalOnly(gcfg.ReadFileInto(&config, path)); err != nil {
+		return config, err
+	}
+
+	for _, overlay := range []string{
+		fmt.Sprintf("%s_%s_%s", path, runtime.GOOS, runtime.GOARCH),
+		path + ".local",
+	} {
+		if _, err := os.Stat(overlay); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := gcfg.FatalOnly(gcfg.ReadFileInto(&config, overlay)); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
 }
 
 func (*Ctl) QueryDeps(targets ...string) ([]string, error) {
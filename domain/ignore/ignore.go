@@ -0,0 +1,192 @@
+// Package ignore matches paths against gitignore style patterns.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a single gitignore style pattern which applies to paths under the
+// directory containing the pattern.
+type Pattern struct {
+	base    string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// Parse parses the gitignore style patterns contained in an ignore file found
+// in the base directory. Blank lines and comments are skipped.
+func Parse(base string, data []byte) ([]Pattern, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return Compile(base, lines...)
+}
+
+// Compile compiles gitignore style patterns which are relative to the base
+// directory. Blank lines and comments are skipped. Invalid patterns, such as
+// those with malformed character classes, are skipped and reported by the
+// returned error alongside the patterns which did compile.
+func Compile(base string, lines ...string) ([]Pattern, error) {
+	base = path.Clean(base)
+	if base == "." {
+		base = ""
+	}
+
+	patterns := make([]Pattern, 0, len(lines))
+
+	var invalid []string
+
+	for _, line := range lines {
+		pattern := line
+
+		line = strings.TrimRight(line, " \t\r")
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := Pattern{base: base}
+
+		switch {
+		case strings.HasPrefix(line, "!"):
+			p.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\`):
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		// Patterns without a slash match at any depth below the base directory
+		// whereas patterns with a slash are anchored to the base directory.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		re, err := regexp.Compile("^" + globRegexp(line) + "$")
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%q", pattern))
+			continue
+		}
+
+		p.re = re
+
+		patterns = append(patterns, p)
+	}
+
+	if len(invalid) > 0 {
+		return patterns, fmt.Errorf("invalid patterns: %s", strings.Join(invalid, ", "))
+	}
+
+	return patterns, nil
+}
+
+func globRegexp(glob string) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(glob[i:], "**/"):
+				buf.WriteString("(?:.*/)?")
+				i += 2
+			case glob[i:] == "**":
+				buf.WriteString(".*")
+				i++
+			default:
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			buf.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return buf.String()
+}
+
+// match reports whether the pattern applies to the path.
+func (p Pattern) match(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(name, p.base+"/") {
+			return false
+		}
+
+		name = name[len(p.base)+1:]
+	}
+
+	return p.re.MatchString(name)
+}
+
+// Matcher is an ordered list of patterns where later patterns take precedence
+// over earlier ones.
+type Matcher []Pattern
+
+// Match reports whether the slash separated path, which is relative to the
+// project root, is ignored. A path is also ignored when any of its parent
+// directories are ignored.
+func (m Matcher) Match(name string, isDir bool) bool {
+	name = path.Clean(name)
+	if name == "." || len(m) == 0 {
+		return false
+	}
+
+	chunks := strings.Split(name, "/")
+
+	for i := range chunks {
+		if m.matchOne(strings.Join(chunks[:i+1], "/"), isDir || i < len(chunks)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m Matcher) matchOne(name string, isDir bool) bool {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].match(name, isDir) {
+			return !m[i].negate
+		}
+	}
+
+	return false
+}
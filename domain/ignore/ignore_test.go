@@ -0,0 +1,136 @@
+package ignore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/ignore"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	for _, tt := range []struct {
+		Name     string
+		Patterns ignore.Matcher
+		Path     string
+		IsDir    bool
+		Want     bool
+	}{{
+		Name:     "matches name at any depth",
+		Patterns: compile(".", "vendor"),
+		Path:     "app/vendor",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "matches anchored pattern relative to base",
+		Patterns: compile("app", "/gen"),
+		Path:     "app/gen",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "does not match anchored pattern outside base",
+		Patterns: compile("app", "/gen"),
+		Path:     "gen",
+		IsDir:    true,
+	}, {
+		Name:     "does not match anchored pattern deeper than base",
+		Patterns: compile("app", "/gen"),
+		Path:     "app/server/gen",
+		IsDir:    true,
+	}, {
+		Name:     "matches paths under ignored directory",
+		Patterns: compile(".", "experiments/"),
+		Path:     "experiments/alice/app",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "does not match files with directory only pattern",
+		Patterns: compile(".", "gen/"),
+		Path:     "app/gen",
+	}, {
+		Name:     "matches single segment wildcards",
+		Patterns: compile(".", "sandbox-*"),
+		Path:     "app/sandbox-1",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "matches paths under wildcard matched directory",
+		Patterns: compile(".", "app/*"),
+		Path:     "app/server/gen",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "matches double star segments",
+		Patterns: compile(".", "app/**/testdata"),
+		Path:     "app/server/api/testdata",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "matches character classes",
+		Patterns: compile(".", "v[0-9]"),
+		Path:     "api/v2",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name: "later negation re-includes path",
+		Patterns: append(
+			compile(".", "gen"),
+			compile("app", "!gen")...,
+		),
+		Path:  "app/gen",
+		IsDir: true,
+	}, {
+		Name: "negation only applies under its base",
+		Patterns: append(
+			compile(".", "gen"),
+			compile("app", "!gen")...,
+		),
+		Path:  "lib/gen",
+		IsDir: true,
+		Want:  true,
+	}, {
+		Name:     "skips comments and blank lines",
+		Patterns: parse(".", []byte("# vendored code\n\nvendor\n")),
+		Path:     "vendor",
+		IsDir:    true,
+		Want:     true,
+	}, {
+		Name:     "never matches root",
+		Patterns: compile(".", "*"),
+		Path:     ".",
+		IsDir:    true,
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.Want, tt.Patterns.Match(tt.Path, tt.IsDir))
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	t.Run("skips and reports invalid patterns", func(t *testing.T) {
+		patterns, err := ignore.Compile(".", "foo[]", "vendor", "[z-a]")
+		require.EqualError(t, err, `invalid patterns: "foo[]", "[z-a]"`)
+		require.Len(t, patterns, 1)
+
+		require.True(t, ignore.Matcher(patterns).Match("app/vendor", true))
+		require.False(t, ignore.Matcher(patterns).Match("app/foo", true))
+	})
+}
+
+func compile(base string, lines ...string) []ignore.Pattern {
+	patterns, err := ignore.Compile(base, lines...)
+	if err != nil {
+		panic(err)
+	}
+
+	return patterns
+}
+
+func parse(base string, data []byte) []ignore.Pattern {
+	patterns, err := ignore.Parse(base, data)
+	if err != nil {
+		panic(err)
+	}
+
+	return patterns
+}
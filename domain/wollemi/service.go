@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/tcncloud/wollemi/domain/ignore"
 	"github.com/tcncloud/wollemi/ports/golang"
 	"github.com/tcncloud/wollemi/ports/logging"
 	"github.com/tcncloud/wollemi/ports/please"
@@ -17,6 +18,8 @@ import (
 
 type Config = wollemi.Config
 type Gofmt = wollemi.Gofmt
+type Naming = wollemi.Naming
+type Unused = wollemi.Unused
 
 func New(
 	log logging.Logger,
@@ -29,14 +32,16 @@ func New(
 	gopkg string,
 ) *Service {
 	return &Service{
-		log:        log,
-		filesystem: filesystem,
-		golang:     golang,
-		please:     please,
-		root:       root,
-		wd:         wd,
-		gopkg:      gopkg,
-		gosrc:      gosrc,
+		log:         log,
+		filesystem:  filesystem,
+		golang:      golang,
+		please:      please,
+		root:        root,
+		wd:          wd,
+		gopkg:       gopkg,
+		gosrc:       gosrc,
+		ignores:     make(map[string]ignore.Matcher),
+		ignoreFiles: make(map[string]ignore.Matcher),
 	}
 }
 
@@ -52,6 +57,29 @@ type Service struct {
 	gopkg      string
 
 	goFormat *goFormat
+
+	// ignores caches the ignore patterns which apply to the children of a
+	// directory whereas ignoreFiles caches only those from .wollemiignore files.
+	ignoreMu    sync.Mutex
+	ignores     map[string]ignore.Matcher
+	ignoreFiles map[string]ignore.Matcher
+
+	plzconfig     please.Config
+	plzconfigOnce sync.Once
+}
+
+// pleaseConfig lazily reads the please config of the project.
+func (this *Service) pleaseConfig() please.Config {
+	this.plzconfigOnce.Do(func() {
+		config, err := this.please.Config(filepath.Join(this.root, ".plzconfig"))
+		if err != nil {
+			this.log.WithError(err).Debug("could not read please config")
+		}
+
+		this.plzconfig = config
+	})
+
+	return this.plzconfig
 }
 
 func (this *Service) validateAbsolutePaths(paths []string) error {
@@ -95,7 +123,7 @@ func (this *Service) FindBuildFile(dir string) (string, error) {
 
 	for _, info := range infos {
 		name := info.Name()
-		if isBuildFile(name) {
+		if this.isBuildFile(name) {
 			paths = append(paths, filepath.Join(dir, name))
 		}
 	}
@@ -114,6 +142,31 @@ func (this *Service) FindBuildFile(dir string) (string, error) {
 	return path, err
 }
 
+// parseBuildFile finds, reads and parses the build file of the directory. Any
+// failure is logged and a nil file returned.
+func (this *Service) parseBuildFile(buf *bytes.Buffer, dir string) please.File {
+	log := this.log.WithField("path", dir)
+
+	buildPath, err := this.FindBuildFile(dir)
+	if err != nil {
+		log.WithError(err).Warn("could not find build file")
+		return nil
+	}
+
+	if err := this.filesystem.ReadAll(buf, buildPath); err != nil {
+		log.WithError(err).Warn("could not read build file")
+		return nil
+	}
+
+	file, err := this.please.Parse(buildPath, buf.Bytes())
+	if err != nil {
+		log.WithError(err).Warn("could not parse build file")
+		return nil
+	}
+
+	return file
+}
+
 func (this *Service) GoSrcPath(elem ...string) string {
 	return filepath.Join(this.gosrc, filepath.Join(elem...))
 }
@@ -143,7 +196,7 @@ func (this *Service) ReadDir(path string) (*Directory, error) {
 			continue
 		}
 
-		if isBuildFile(name) {
+		if this.isBuildFile(name) {
 			dir.BuildFiles = append(dir.BuildFiles, name)
 		}
 
@@ -169,12 +222,20 @@ func (this *Service) ReadDirs(out chan *Directory, paths ...string) error {
 					path = filepath.Dir(path)
 				}
 
+				if this.isIgnored(path) {
+					this.log.WithField("path", path).Debug("ignored")
+					wg.Done()
+					continue
+				}
+
 				dir, err := this.ReadDir(path)
 				if err != nil {
 					wg.Done()
 					continue
 				}
 
+				this.ignoreFilePatterns(dir.Path, dir.Files)
+
 				for name, info := range dir.Files {
 					if info.IsDir() {
 						if name != "." && strings.HasPrefix(name, ".") {
@@ -186,6 +247,11 @@ func (this *Service) ReadDirs(out chan *Directory, paths ...string) error {
 						}
 
 						path := filepath.Join(dir.Path, name)
+						if this.isIgnored(path) {
+							this.log.WithField("path", path).Debug("ignored")
+							continue
+						}
+
 						if recursive {
 							path = filepath.Join(path, "...")
 						}
@@ -221,6 +287,124 @@ func (this *Service) ReadDirs(out chan *Directory, paths ...string) error {
 	return nil
 }
 
+// isIgnored determines if the directory at path is excluded by the ignore
+// patterns which apply to its parent directory.
+func (this *Service) isIgnored(path string) bool {
+	path = filepath.Clean(path)
+	if path == "." {
+		return false
+	}
+
+	return this.ignorePatterns(filepath.Dir(path)).Match(path, true)
+}
+
+// ignorePatterns returns the ignore patterns which apply to the children of the
+// directory. These are the please blacklisted directories, the ignore patterns
+// of every config file found between the project root and the directory, which
+// are relative to the directory of the config file declaring them, and the
+// overridden config ignore patterns, followed by the patterns of every ignore
+// file found between the project root and the directory.
+func (this *Service) ignorePatterns(dir string) ignore.Matcher {
+	this.ignoreMu.Lock()
+	patterns, ok := this.ignores[dir]
+	this.ignoreMu.Unlock()
+
+	if ok {
+		return patterns
+	}
+
+	patterns = this.compileIgnore(".", ".plzconfig", this.pleaseConfig().Parse.BlacklistDirs)
+
+	for _, file := range this.filesystem.ConfigFiles(dir) {
+		base := file.Dir
+		if base == "" {
+			base = "."
+		}
+
+		patterns = append(patterns, this.compileIgnore(base, file.Path, file.Config.Ignore)...)
+	}
+
+	patterns = append(patterns, this.compileIgnore(".", "", this.config.Ignore)...)
+	patterns = append(patterns, this.ignoreFilePatterns(dir, nil)...)
+
+	this.ignoreMu.Lock()
+	this.ignores[dir] = patterns
+	this.ignoreMu.Unlock()
+
+	return patterns
+}
+
+// compileIgnore compiles the ignore patterns, relative to the base directory,
+// which were read from the file. Invalid patterns are reported and skipped.
+func (this *Service) compileIgnore(base, file string, lines []string) []ignore.Pattern {
+	patterns, err := ignore.Compile(base, lines...)
+	if err != nil {
+		log := this.log.WithError(err)
+		if file != "" {
+			log = log.WithField("file", file)
+		}
+
+		log.Warn("skipped ignore patterns")
+	}
+
+	return patterns
+}
+
+// ignoreFiles are the files, in order of precedence, whose gitignore style
+// patterns apply to the directory they are found in and every directory below.
+var ignoreFiles = []string{".gitignore", ".wollemiignore"}
+
+// ignoreFilePatterns returns the patterns of every ignore file found between
+// the project root and the directory. When given the files listed in the
+// directory are used to find its ignore files.
+func (this *Service) ignoreFilePatterns(dir string, files map[string]os.FileInfo) ignore.Matcher {
+	this.ignoreMu.Lock()
+	patterns, ok := this.ignoreFiles[dir]
+	this.ignoreMu.Unlock()
+
+	if ok {
+		return patterns
+	}
+
+	if dir != "." {
+		patterns = append(patterns, this.ignoreFilePatterns(filepath.Dir(dir), nil)...)
+	}
+
+	for _, name := range ignoreFiles {
+		path := filepath.Join(dir, name)
+
+		exists := false
+		if files != nil {
+			_, exists = files[name]
+		} else if _, err := this.filesystem.Stat(path); err == nil {
+			exists = true
+		}
+
+		if !exists {
+			continue
+		}
+
+		var buf bytes.Buffer
+
+		if err := this.filesystem.ReadAll(&buf, path); err != nil {
+			this.log.WithError(err).WithField("file", path).Warn("could not read file")
+		} else {
+			parsed, err := ignore.Parse(dir, buf.Bytes())
+			if err != nil {
+				this.log.WithError(err).WithField("file", path).Warn("skipped ignore patterns")
+			}
+
+			patterns = append(patterns, parsed...)
+		}
+	}
+
+	this.ignoreMu.Lock()
+	this.ignoreFiles[dir] = patterns
+	this.ignoreMu.Unlock()
+
+	return patterns
+}
+
 func (this *Service) ParseDir(buf *bytes.Buffer, dir *Directory) *Directory {
 	log := this.log.WithField("path", dir.Path)
 
@@ -237,7 +421,7 @@ func (this *Service) ParseDir(buf *bytes.Buffer, dir *Directory) *Directory {
 	var buildPath string
 
 	if len(dir.BuildFiles) == 0 {
-		buildPath = filepath.Join(dir.Path, BUILD_FILE)
+		buildPath = filepath.Join(dir.Path, this.buildFileNames()[0])
 	} else {
 		buildPath = filepath.Join(dir.Path, dir.BuildFiles[0])
 
@@ -0,0 +1,174 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// BuildfilesMerge merges every directory under the provided paths which
+// contains more than one build file into a single build file using the most
+// preferred build file name. Rules only found in the other build files are
+// moved over while rules defined in several build files are combined. A
+// directory is left untouched when its build files define the same rule with
+// a different kind or conflicting attributes, or contain statements other than
+// function calls.
+func (this *Service) BuildfilesMerge(paths []string) error {
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return err
+	}
+
+	paths = this.normalizePaths(paths)
+
+	walk := make(chan *Directory, 1000)
+
+	if err := this.ReadDirs(walk, paths...); err != nil {
+		return fmt.Errorf("could not walk: %v", err)
+	}
+
+	var buf bytes.Buffer
+	var ambiguous, failed int
+
+	for dir := range walk {
+		if len(dir.BuildFiles) < 2 {
+			continue
+		}
+
+		ambiguous++
+
+		if !this.mergeBuildFiles(&buf, dir) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d directories could not be merged", failed, ambiguous)
+	}
+
+	return nil
+}
+
+// mergeBuildFiles merges the build files of the directory into the build file
+// with the most preferred name and removes the others. Everything which could
+// not be merged is logged and false is returned.
+func (this *Service) mergeBuildFiles(buf *bytes.Buffer, dir *Directory) bool {
+	log := this.log.WithField("path", dir.Path)
+
+	names := this.buildFileNames()
+
+	sort.SliceStable(dir.BuildFiles, func(i, j int) bool {
+		return indexStrings(names, dir.BuildFiles[i]) < indexStrings(names, dir.BuildFiles[j])
+	})
+
+	files := make([]please.File, 0, len(dir.BuildFiles))
+
+	for _, name := range dir.BuildFiles {
+		path := filepath.Join(dir.Path, name)
+
+		if err := this.filesystem.ReadAll(buf, path); err != nil {
+			log.WithError(err).WithField("file", path).Warn("could not read build file")
+			return false
+		}
+
+		file, err := this.please.Parse(path, buf.Bytes())
+		if err != nil {
+			log.WithError(err).WithField("file", path).Warn("could not parse build file")
+			return false
+		}
+
+		files = append(files, file)
+	}
+
+	into := files[0]
+	ok := true
+
+	for _, from := range files[1:] {
+		flog := log.WithField("file", from.GetPath())
+
+		if n := from.NumOtherStmts(); n > 0 {
+			flog.WithField("statements", n).
+				Warn("could not merge statements which are not rules")
+
+			ok = false
+		}
+
+		from.GetRules(func(rule please.Rule) {
+			name := rule.Name()
+			if name == "" {
+				if !hasEqualRule(into, rule) {
+					into.AddRule(rule)
+				}
+
+				return
+			}
+
+			have := into.GetRule(name)
+			if have == nil {
+				into.AddRule(rule)
+				return
+			}
+
+			rlog := flog.WithField("rule", name)
+
+			if have.Kind() != rule.Kind() {
+				rlog.WithField("kinds", []string{have.Kind(), rule.Kind()}).
+					Warn("could not merge rules of different kinds")
+
+				ok = false
+
+				return
+			}
+
+			for _, key := range rule.AttrKeys() {
+				attr := rule.Attr(key)
+
+				if expr := have.Attr(key); expr == nil {
+					have.SetAttr(key, attr)
+				} else if !reflect.DeepEqual(expr, attr) {
+					rlog.WithField("attr", key).Warn("could not merge conflicting attribute")
+					ok = false
+				}
+			}
+		})
+	}
+
+	if !ok {
+		return false
+	}
+
+	if err := this.please.Write(into); err != nil {
+		log.WithError(err).WithField("file", into.GetPath()).Warn("could not write")
+		return false
+	}
+
+	for _, from := range files[1:] {
+		if err := this.filesystem.Remove(from.GetPath()); err != nil {
+			log.WithError(err).WithField("file", from.GetPath()).Warn("could not remove")
+			return false
+		}
+	}
+
+	log.WithField("file", into.GetPath()).
+		WithField("merged", dir.BuildFiles[1:]).
+		Info("merged build files")
+
+	return true
+}
+
+// hasEqualRule determines if the file already contains a rule identical to the
+// provided rule.
+func hasEqualRule(file please.File, rule please.Rule) bool {
+	var found bool
+
+	file.GetRules(func(have please.Rule) {
+		if !found && reflect.DeepEqual(have.Unwrap(), rule.Unwrap()) {
+			found = true
+		}
+	})
+
+	return found
+}
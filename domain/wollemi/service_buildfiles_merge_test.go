@@ -0,0 +1,188 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tcncloud/wollemi/domain/wollemi"
+	"github.com/tcncloud/wollemi/testdata/expect"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_BuildfilesMerge(t *testing.T) {
+	NewServiceSuite(t).TestService_BuildfilesMerge()
+}
+
+func (t *ServiceSuite) TestService_BuildfilesMerge() {
+	type T = ServiceSuite
+
+	t.It("merges ambiguous build files into the preferred build file", func(t *T) {
+		t.MockBuildfilesMerge(map[string]*please.BuildFile{
+			"app/BUILD.plz": &please.BuildFile{
+				Path: "app/BUILD.plz",
+				Stmt: []please.Expr{
+					please.NewCallExpr("subinclude", []please.Expr{
+						please.NewStringExpr("//build_defs:go"),
+					}),
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"})),
+					}),
+				},
+			},
+			"app/BUILD": &please.BuildFile{
+				Path: "app/BUILD",
+				Stmt: []please.Expr{
+					please.NewCallExpr("subinclude", []please.Expr{
+						please.NewStringExpr("//build_defs:go"),
+					}),
+					please.NewCallExpr("subinclude", []please.Expr{
+						please.NewStringExpr("//build_defs:k8s"),
+					}),
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"})),
+						please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+					}),
+					please.NewCallExpr("filegroup", []please.Expr{
+						please.NewAssignExpr("=", "name", "files"),
+						please.NewAssignExpr("=", "srcs", []string{"config.yaml"}),
+					}),
+				},
+			},
+		})
+
+		t.please.EXPECT().Write(any).Times(1).Do(func(have please.File) {
+			expect.Equal(t, &please.BuildFile{
+				Path: "app/BUILD.plz",
+				Stmt: []please.Expr{
+					please.NewCallExpr("subinclude", []please.Expr{
+						please.NewStringExpr("//build_defs:go"),
+					}),
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"})),
+						please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+					}),
+					please.NewCallExpr("subinclude", []please.Expr{
+						please.NewStringExpr("//build_defs:k8s"),
+					}),
+					please.NewCallExpr("filegroup", []please.Expr{
+						please.NewAssignExpr("=", "name", "files"),
+						please.NewAssignExpr("=", "srcs", []string{"config.yaml"}),
+					}),
+				},
+			}, have)
+		})
+
+		t.filesystem.EXPECT().Remove("app/BUILD").Return(nil)
+
+		err := t.New(root, wd, gosrc, gopkg).BuildfilesMerge([]string{"app/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("does not merge build files which define conflicting rules", func(t *T) {
+		t.MockBuildfilesMerge(map[string]*please.BuildFile{
+			"app/BUILD.plz": &please.BuildFile{
+				Path: "app/BUILD.plz",
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+					}),
+					please.NewCallExpr("filegroup", []please.Expr{
+						please.NewAssignExpr("=", "name", "files"),
+					}),
+				},
+			},
+			"app/BUILD": &please.BuildFile{
+				Path: "app/BUILD",
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+					}),
+					please.NewCallExpr("genrule", []please.Expr{
+						please.NewAssignExpr("=", "name", "files"),
+					}),
+					please.NewAssignExpr("=", "version", "v1.0.0"),
+				},
+			},
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).BuildfilesMerge([]string{"app/..."})
+		assert.EqualError(t, err, "1 of 1 directories could not be merged")
+
+		var msgs []interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "warn" {
+				msgs = append(msgs, entry["msg"])
+			}
+		}
+
+		assert.ElementsMatch(t, []interface{}{
+			"could not merge statements which are not rules",
+			"could not merge conflicting attribute",
+			"could not merge rules of different kinds",
+		}, msgs)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).BuildfilesMerge([]string{"/outside/of/root"})
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockBuildfilesMerge(files map[string]*please.BuildFile) {
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) ([]os.FileInfo, error) {
+			var infos []os.FileInfo
+
+			for name := range files {
+				if filepath.Dir(name) == path {
+					infos = append(infos, &FileInfo{
+						FileName: filepath.Base(name),
+						FileMode: os.FileMode(420),
+					})
+				}
+			}
+
+			if infos == nil {
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			return infos, nil
+		})
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			if _, ok := files[path]; !ok {
+				t.Errorf("unexpected call to filesystem read all: %s", path)
+			}
+
+			buf.Reset()
+			buf.WriteString(path)
+
+			return nil
+		})
+
+	t.please.EXPECT().Parse(any, any).AnyTimes().
+		DoAndReturn(func(path string, data []byte) (please.File, error) {
+			assert.Equal(t, string(data), path)
+
+			return files[path], nil
+		})
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
@@ -0,0 +1,41 @@
+package wollemi
+
+import (
+	"fmt"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+// ConfigShow returns the merged config for the provided directory where each
+// field is annotated with the config file which set it. Fields which were not
+// set by any config file are annotated with the source "default".
+func (this *Service) ConfigShow(path string) (wollemi.ConfigProvenance, error) {
+	paths := []string{"."}
+	if path != "" {
+		paths = []string{path}
+	}
+
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return nil, err
+	}
+
+	path = this.normalizePaths(paths)[0]
+
+	info, err := this.filesystem.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", path)
+	}
+
+	files := []wollemi.ConfigFile{{
+		Path:   "default",
+		Config: wollemi.DefaultConfig(),
+	}}
+
+	files = append(files, this.filesystem.ConfigFiles(path)...)
+
+	return wollemi.Provenance(files...), nil
+}
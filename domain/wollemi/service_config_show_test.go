@@ -0,0 +1,72 @@
+package wollemi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestService_ConfigShow(t *testing.T) {
+	NewServiceSuite(t).TestService_ConfigShow()
+}
+
+func (t *ServiceSuite) TestService_ConfigShow() {
+	type T = ServiceSuite
+
+	t.It("annotates the merged directory config with its sources", func(t *T) {
+		t.filesystem.EXPECT().Stat("app/server").
+			Return(&FileInfo{FileName: "server", FileIsDir: true}, nil)
+
+		t.filesystem.EXPECT().ConfigFiles("app/server").
+			Return([]wollemi.ConfigFile{{
+				Path: "app/.wollemi.json",
+				Config: wollemi.Config{
+					ExplicitSources: optional.BoolValue(true),
+				},
+			}})
+
+		have, err := t.New(root, wd, gosrc, gopkg).ConfigShow("app/server")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"source": "app/.wollemi.json",
+			"value":  true,
+		}, have["explicit_sources"])
+
+		assert.Equal(t, map[string]interface{}{
+			"source": "default",
+			"value":  false,
+		}, have["allow_unresolved_dependency"])
+	})
+
+	t.It("shows the config for the working directory when no path given", func(t *T) {
+		wd := filepath.Join(root, "app")
+
+		t.filesystem.EXPECT().Stat("app").
+			Return(&FileInfo{FileName: "app", FileIsDir: true}, nil)
+
+		t.filesystem.EXPECT().ConfigFiles("app").Return(nil)
+
+		_, err := t.New(root, wd, gosrc, gopkg).ConfigShow("")
+		require.NoError(t, err)
+	})
+
+	t.It("returns an error when the path is not a directory", func(t *T) {
+		t.filesystem.EXPECT().Stat("app/main.go").
+			Return(&FileInfo{FileName: "main.go", FileMode: os.FileMode(420)}, nil)
+
+		_, err := t.New(root, wd, gosrc, gopkg).ConfigShow("app/main.go")
+		assert.Error(t, err)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		_, err := t.New(root, wd, gosrc, gopkg).ConfigShow("/outside/of/root")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,95 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+// ConfigValidate strictly decodes every .wollemi.json config file found under
+// the provided paths along with every config file they extend. Each invalid
+// config file is logged with the line and key at fault and an error is
+// returned when any config file is invalid.
+func (this *Service) ConfigValidate(paths []string) error {
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return err
+	}
+
+	paths = this.normalizePaths(paths)
+
+	walk := make(chan *Directory, 1000)
+
+	if err := this.ReadDirs(walk, paths...); err != nil {
+		return fmt.Errorf("could not walk: %v", err)
+	}
+
+	var buf bytes.Buffer
+	var invalid int
+	var extends []string
+
+	seen := make(map[string]struct{})
+
+	validate := func(path string) {
+		if _, ok := seen[path]; ok {
+			return
+		}
+
+		seen[path] = struct{}{}
+
+		log := this.log.WithField("file", path)
+
+		if err := this.filesystem.ReadAll(&buf, path); err != nil {
+			invalid++
+			log.WithError(err).Error("could not read file")
+			return
+		}
+
+		config := wollemi.Config{}
+
+		err := wollemi.Unmarshal(buf.Bytes(), &config)
+		if err == nil {
+			for _, extend := range config.Extends {
+				extends = append(extends, filepath.Clean(extend))
+			}
+
+			log.Debug("valid config")
+			return
+		}
+
+		invalid++
+
+		if e, ok := err.(*wollemi.ConfigError); ok {
+			log = log.WithField("line", e.Line).
+				WithField("column", e.Column)
+
+			if e.Key != "" {
+				log = log.WithField("key", e.Key)
+			}
+
+			err = e.Err
+		}
+
+		log.WithError(err).Error("invalid config")
+	}
+
+	for dir := range walk {
+		if _, ok := dir.Files[".wollemi.json"]; ok {
+			validate(filepath.Join(dir.Path, ".wollemi.json"))
+		}
+	}
+
+	for len(extends) > 0 {
+		path := extends[0]
+		extends = extends[1:]
+
+		validate(path)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d config files are invalid", invalid, len(seen))
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tcncloud/wollemi/domain/wollemi"
+	wollemiport "github.com/tcncloud/wollemi/ports/wollemi"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_ConfigValidate(t *testing.T) {
+	NewServiceSuite(t).TestService_ConfigValidate()
+}
+
+func (t *ServiceSuite) TestService_ConfigValidate() {
+	type T = ServiceSuite
+
+	t.It("reports invalid config files", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			"app/.wollemi.json":        `{"explicit_sources": true}`,
+			"app/server/.wollemi.json": "{\n  \"gofmt\": {\"crate\": []}\n}",
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.EqualError(t, err, "1 of 2 config files are invalid")
+
+		var lines []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "error" {
+				delete(entry, "time")
+				entry["error"] = fmt.Sprint(entry["error"])
+				lines = append(lines, entry)
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":  "error",
+			"msg":    "invalid config",
+			"error":  "unknown key",
+			"file":   "app/server/.wollemi.json",
+			"line":   2,
+			"column": 13,
+			"key":    "gofmt.crate",
+		}}, lines)
+	})
+
+	t.It("validates the config files extended by config files", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			"app/.wollemi.json":        `{"extends": ["shared/wollemi.json"]}`,
+			"app/server/.wollemi.json": `{"extends": ["shared/wollemi.json", "shared/missing.json"]}`,
+			"shared/wollemi.json":      `{"extends": ["shared/base.json"]}`,
+			"shared/base.json":         `{"known_dependencies": {}}`,
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.EqualError(t, err, "2 of 5 config files are invalid")
+
+		var files []interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "error" {
+				files = append(files, entry["file"])
+			}
+		}
+
+		assert.ElementsMatch(t, []interface{}{
+			"shared/base.json",
+			"shared/missing.json",
+		}, files)
+	})
+
+	t.It("skips directories matched by ignore files", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			".wollemiignore":           "# generated\n/app/server/\n",
+			"app/.wollemi.json":        `{"explicit_sources": true}`,
+			"app/server/.wollemi.json": "{\n  \"gofmt\": {\"crate\": []}\n}",
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("skips directories matched by config ignore patterns relative to the config file", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			"app/.wollemi.json":        `{"explicit_sources": true, "ignore": ["/server/", "foo[]"]}`,
+			"app/server/.wollemi.json": "{\n  \"gofmt\": {\"crate\": []}\n}",
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.NoError(t, err)
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "warn" {
+				delete(entry, "time")
+				have = append(have, entry)
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level": "warn",
+			"msg":   "skipped ignore patterns",
+			"file":  "app/.wollemi.json",
+			"error": fmt.Errorf(`invalid patterns: "foo[]"`),
+		}}, have)
+	})
+
+	t.It("skips directories matched by git ignore files", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			".gitignore":               "node_modules\nserver/\n",
+			"app/.wollemi.json":        `{"explicit_sources": true}`,
+			"app/server/.wollemi.json": "{\n  \"gofmt\": {\"crate\": []}\n}",
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("skips directories blacklisted by the please config", func(t *T) {
+		config := please.Config{}
+		config.Parse.BlacklistDirs = []string{"node_modules", "server"}
+
+		t.please.EXPECT().Config(filepath.Join(root, ".plzconfig")).Return(config, nil)
+
+		t.MockConfigValidate(map[string]string{
+			"app/.wollemi.json":        `{"explicit_sources": true}`,
+			"app/server/.wollemi.json": "{\n  \"gofmt\": {\"crate\": []}\n}",
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("returns no error when all config files are valid", func(t *T) {
+		t.MockConfigValidate(map[string]string{
+			"app/.wollemi.json":        `{"explicit_sources": true}`,
+			"app/server/.wollemi.json": `{"gofmt": {"create": "off"}}`,
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"app/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).ConfigValidate([]string{"/outside/of/root"})
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockConfigValidate(files map[string]string) {
+	// Config files extended by the provided config files are read even when
+	// they are missing so they can be reported.
+	extended := make(map[string]bool)
+	for _, data := range files {
+		var config struct {
+			Extends []string `json:"extends"`
+		}
+
+		if json.Unmarshal([]byte(data), &config) == nil {
+			for _, path := range config.Extends {
+				extended[path] = true
+			}
+		}
+	}
+
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) (infos []os.FileInfo, err error) {
+			switch path {
+			case "app":
+				infos = []os.FileInfo{
+					&FileInfo{
+						FileName: ".wollemi.json",
+						FileMode: os.FileMode(420),
+					},
+					&FileInfo{
+						FileName:  "server",
+						FileIsDir: true,
+					},
+				}
+			case "app/server":
+				infos = []os.FileInfo{
+					&FileInfo{
+						FileName: ".wollemi.json",
+						FileMode: os.FileMode(420),
+					},
+				}
+			default:
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				err = os.ErrNotExist
+			}
+
+			return infos, err
+		})
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			data, ok := files[path]
+			if !ok {
+				if !extended[path] {
+					t.Errorf("unexpected call to filesystem read all: %s", path)
+				}
+
+				return os.ErrNotExist
+			}
+
+			buf.Reset()
+			buf.WriteString(data)
+
+			return nil
+		})
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().
+		DoAndReturn(func(path string) (os.FileInfo, error) {
+			if _, ok := files[path]; !ok {
+				return nil, os.ErrNotExist
+			}
+
+			return &FileInfo{FileName: filepath.Base(path), FileMode: os.FileMode(420)}, nil
+		})
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().
+		DoAndReturn(func(path string) []wollemiport.ConfigFile {
+			var configs []wollemiport.ConfigFile
+
+			for dir := path; ; dir = filepath.Dir(dir) {
+				file := filepath.Join(dir, ".wollemi.json")
+
+				config := wollemi.Config{}
+				if data, ok := files[file]; ok && wollemiport.Unmarshal([]byte(data), &config) == nil {
+					configs = append([]wollemiport.ConfigFile{{Path: file, Config: config, Dir: dir}}, configs...)
+				}
+
+				if dir == "." {
+					break
+				}
+			}
+
+			return configs
+		})
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tcncloud/wollemi/domain/optional"
 	"github.com/tcncloud/wollemi/ports/logging"
@@ -15,10 +17,6 @@ import (
 	"github.com/tcncloud/wollemi/ports/wollemi"
 )
 
-const (
-	BUILD_FILE = "BUILD.plz"
-)
-
 func newGoFormat(paths []string) *goFormat {
 	return &goFormat{
 		resolveLimiter: NewChanFunc(1, 0),
@@ -26,8 +24,16 @@ func newGoFormat(paths []string) *goFormat {
 		paths:          paths,
 		directories:    map[string]*Directory{},
 		external:       map[string][]string{},
+		modules:        map[string][]string{},
+		changed:        map[string]bool{},
+		created:        map[string]please.File{},
+		pendingRules:   map[string][]please.Rule{},
+		pendingInstall: map[string][]string{},
+		suggested:      map[string]bool{},
 		internal:       map[string]string{},
 		genfiles:       map[string]string{},
+		visibility:     map[string][]string{},
+		widen:          map[string][]string{},
 	}
 }
 
@@ -49,18 +55,55 @@ type goFormat struct {
 	// external is a map of third party imports to build targets
 	external map[string][]string
 
+	// modules is a map of go modules to the go_module targets which install their packages
+	modules map[string][]string
+
+	// changed contains the directories whose build files were changed while resolving third party imports
+	changed map[string]bool
+
+	// created contains the build files created for go_module rules of unresolved imports, keyed by directory
+	created map[string]please.File
+
+	// pendingRules contains the go_module rules to add to parsed build files once formatting is done, keyed by directory
+	pendingRules map[string][]please.Rule
+
+	// pendingInstall contains the install entries to add to go_module rules of parsed build files once formatting is
+	// done, keyed by label
+	pendingInstall map[string][]string
+
+	// suggested contains the unresolved imports a go_module rule was already suggested for
+	suggested map[string]bool
+
+	// goSum contains the module versions of the go.sum of the repo, once read
+	goSum map[string][]string
+
 	// internal is a map of this projects imports paths to targets
 	internal map[string]string
 
 	// genfiles contain a map of generated files added via go_copy() to their build targets
 	genfiles map[string]string
+
+	// visibility contains the visibility of every parsed rule, keyed by path:name, as it was before formatting
+	visibility map[string][]string
+
+	// widen contains the packages to add to the visibility of a rule, keyed by path:name
+	widen   map[string][]string
+	widenMu sync.Mutex
 }
 
 // getTarget gets the target for an import path or generated file
 func (this *Service) getTarget(config wollemi.Config, p string, isFile bool) string {
 	var target string
 	this.goFormat.resolveLimiter.RunBlock(func() {
+		if !isFile && config.Gofmt.GetInstallMissing() {
+			this.installMissing(config, p)
+		}
+
 		target, _ = this.getTargetInternal(config, p, isFile, 0)
+
+		if target == "" && !isFile && !config.AllowUnresolvedDependency.IsTrue() {
+			target = this.resolveGoModule(config, p)
+		}
 	})
 	return target
 }
@@ -84,7 +127,7 @@ func (this *Service) getTargetInternal(config wollemi.Config, path string, isFil
 		}
 
 		if _, ok := this.goFormat.directories[path]; ok {
-			return fmt.Sprintf("//%s", path), path
+			return this.libraryLabel(path), path
 		}
 
 		if this.isInternal(path) {
@@ -99,12 +142,12 @@ func (this *Service) getTargetInternal(config wollemi.Config, path string, isFil
 			}
 
 			if path == this.gopkg {
-				path = fmt.Sprintf(":%s", filepath.Base(this.wd))
-			} else {
-				path = strings.TrimPrefix(path, this.gopkg+"/")
+				return this.libraryLabel("."), fmt.Sprintf(":%s", filepath.Base(this.wd))
 			}
 
-			return fmt.Sprintf("//%s", path), path
+			path = strings.TrimPrefix(path, this.gopkg+"/")
+
+			return this.libraryLabel(path), path
 		}
 	}
 
@@ -128,6 +171,96 @@ func (this *Service) getTargetInternal(config wollemi.Config, path string, isFil
 	return this.getTargetInternal(config, path, isFile, depth+1)
 }
 
+// installMissing adds the third party package to the install list of the
+// go_module rule of its module when no rule installs the package yet, so the
+// import resolves to that rule rather than to a rule installing a parent
+// package. Rules of parsed build files are only changed once formatting is
+// done, see applyModuleChanges.
+func (this *Service) installMissing(config wollemi.Config, pkg string) {
+	if _, ok := config.KnownDependency[pkg]; ok || this.isInternal(pkg) {
+		return
+	}
+
+	if _, ok := this.goFormat.external[pkg]; ok {
+		return
+	}
+
+	var module string
+
+	for path := range this.goFormat.modules {
+		if (pkg == path || strings.HasPrefix(pkg, path+"/")) && len(path) > len(module) {
+			module = path
+		}
+	}
+
+	if module == "" {
+		return
+	}
+
+	label := this.goFormat.modules[module][0]
+
+	rule, added := this.moduleRule(label)
+	if rule == nil {
+		return
+	}
+
+	install := rule.AttrStrings("install")
+	if len(install) == 0 {
+		install = []string{"."} // Empty install list installs the root package
+	}
+
+	install = append(install, this.goFormat.pendingInstall[label]...)
+
+	for _, entry := range install {
+		if installsPackage(module, entry, pkg) {
+			return
+		}
+	}
+
+	entry := "."
+	if pkg != module {
+		entry = strings.TrimPrefix(pkg, module+"/")
+	}
+
+	if added {
+		rule.SetAttr("install", please.Strings(append(install, entry)...))
+	} else {
+		this.goFormat.pendingInstall[label] = append(this.goFormat.pendingInstall[label], entry)
+	}
+
+	target := please.Split(label)
+
+	this.goFormat.external[pkg] = append(this.goFormat.external[pkg], target.String())
+
+	this.log.WithField("path", filepath.Join("/", target.Path)).
+		WithField("rule", target.Name).
+		WithField("module", module).
+		WithField("install", entry).
+		Info("installed missing package")
+}
+
+// libraryLabel returns the label of the go_library rule which gofmt names in
+// the provided directory according to the naming config of that directory.
+func (this *Service) libraryLabel(path string) string {
+	var pkg string
+	if dir, ok := this.goFormat.directories[path]; ok && dir.Gopkg != nil {
+		pkg = dir.Gopkg.Name
+	}
+
+	config := this.filesystem.Config(path).Merge(this.config)
+	base := filepath.Base(filepath.Join(this.wd, path))
+	name := config.Gofmt.GetName("go_library", base, pkg)
+
+	switch {
+	case path == ".":
+		return fmt.Sprintf("//:%s", name)
+	case name == filepath.Base(path):
+		return fmt.Sprintf("//%s", path)
+	default:
+		return fmt.Sprintf("//%s:%s", path, name)
+	}
+}
+
 // parsePaths will start parsing the Please packages to be formatted. It populates the directories map on the goFormat
 // struct. These can later be formatted with formatDirs().
 func (this *Service) parsePaths() error {
@@ -155,6 +288,8 @@ func (this *Service) parsePaths() error {
 	delegated := make(map[string]struct{})
 	parsing := 0
 
+	thirdPartyDirs := this.filesystem.Config(".").Merge(this.config).GetThirdPartyDirs()
+
 	for walk != nil || parsing > 0 {
 		select {
 		case dir, ok := <-walk:
@@ -193,56 +328,66 @@ func (this *Service) parsePaths() error {
 							continue
 						}
 
-						path := godep
+						var paths []string
 
-						if this.isInternal(path) {
-							path = strings.TrimPrefix(path, this.gopkg+"/")
+						if this.isInternal(godep) {
+							paths = []string{strings.TrimPrefix(godep, this.gopkg+"/")}
 						} else {
-							path = filepath.Join("third_party/go", path)
+							for _, thirdPartyDir := range thirdPartyDirs {
+								paths = append(paths, filepath.Join(thirdPartyDir, godep))
+							}
 						}
 
 						if _, ok := this.goFormat.external[godep]; ok {
 							continue
 						}
 
-						if inRunPath(path, this.goFormat.paths...) {
-							continue
+						for _, path := range paths {
+							if inRunPath(path, this.goFormat.paths...) {
+								continue Imports
+							}
 						}
 
-						chunks := strings.Split(path, "/")
+						for _, path := range paths {
+							if this.isIgnored(path) {
+								continue
+							}
 
-						for i := len(chunks); i > 0; i-- {
-							path := filepath.Join(chunks[0:i]...)
+							chunks := strings.Split(path, "/")
 
-							if _, ok := delegated[path]; ok {
-								continue Imports
-							}
+							for i := len(chunks); i > 0; i-- {
+								path := filepath.Join(chunks[0:i]...)
 
-							if _, ok := this.goFormat.directories[path]; ok {
-								continue Imports
-							}
+								if _, ok := delegated[path]; ok {
+									continue Imports
+								}
 
-							dir, err := this.ReadDir(path)
-							if os.IsNotExist(err) {
-								continue
-							}
+								if _, ok := this.goFormat.directories[path]; ok {
+									continue Imports
+								}
 
-							if err != nil {
-								this.log.WithError(err).
-									WithField("path", path).
-									Warn("could not read dir")
+								dir, err := this.ReadDir(path)
+								if os.IsNotExist(err) {
+									continue
+								}
 
-								continue
-							}
+								if err != nil {
+									this.log.WithError(err).
+										WithField("path", path).
+										Warn("could not read dir")
 
-							if len(dir.BuildFiles) == 0 {
-								continue
-							}
+									continue
+								}
+
+								if len(dir.BuildFiles) == 0 {
+									continue
+								}
 
-							delegated[path] = struct{}{}
+								delegated[path] = struct{}{}
 
-							parsing++
-							parse <- dir
+								parsing++
+								parse <- dir
+							}
 						}
 					}
 				}
@@ -272,24 +417,16 @@ func (this *Service) parsePaths() error {
 
 						this.goFormat.external[path] = append(this.goFormat.external[path], target.String())
 					}
+
+					this.goFormat.modules[module] = append(this.goFormat.modules[module], target.String())
 				case "go_get", "go_get_with_sources":
-					get := strings.TrimSuffix(rule.AttrString("get"), "/...")
-					if get == "" {
-						if install := rule.AttrStrings("install"); len(install) > 0 {
-							sort.Strings(install)
-							get = strings.TrimSuffix(install[0], "/...")
-						}
-					}
+					get := goGetPath(rule)
 
 					target := &please.Target{
 						Name: rule.AttrString("name"),
 						Path: dir.Path,
 					}
 
-					if rule.Kind() == "go_get_with_sources" {
-						get = rule.AttrStrings("outs")[0]
-					}
-
 					if get != "" && rule.AttrLiteral("binary") != "True" {
 						this.goFormat.external[get] = append(this.goFormat.external[get], target.String())
 					}
@@ -312,7 +449,7 @@ func (this *Service) parsePaths() error {
 					switch {
 					case importPath != "":
 						this.goFormat.external[importPath] = append(this.goFormat.external[path], "//"+target)
-					case strings.HasPrefix(path, "third_party/go/"):
+					case inThirdPartyDir(path, thirdPartyDirs):
 					case kind != "go_test":
 						this.goFormat.internal[filepath.Join(this.gopkg, path)] = "//" + target
 
@@ -331,8 +468,9 @@ func (this *Service) parsePaths() error {
 
 // formatDirs updated the BUILD file in the directories that were in the original paths
 func (this *Service) formatDirs() {
+	this.collectVisibility()
+
 	limiter := NewChanFunc(runtime.NumCPU()-1, 0)
-	defer limiter.Close()
 
 	for path, dir := range this.goFormat.directories {
 		if !dir.InRunPath {
@@ -344,12 +482,401 @@ func (this *Service) formatDirs() {
 
 		limiter.Run(func() {
 			this.formatDir(log, dir)
+		})
+	}
+
+	limiter.Close()
+
+	this.inferTestOnly()
+	this.checkTestOnlyDeps()
+	this.applyModuleChanges()
+
+	// Directories outside of the run paths are only written when one of their
+	// rules changed, either by widening its visibility or while resolving third
+	// party imports.
+	changed := this.widenVisibility()
+
+	for path := range this.goFormat.changed {
+		changed[path] = true
+	}
+
+	limiter = NewChanFunc(runtime.NumCPU()-1, 0)
+	defer limiter.Close()
+
+	for path, dir := range this.goFormat.directories {
+		if !dir.InRunPath && !changed[path] {
+			continue
+		}
+
+		log := this.log.WithField("path", filepath.Join("/", path))
+		dir := dir
 
+		limiter.Run(func() {
 			if err := this.please.Write(dir.Build); err != nil {
 				log.WithError(err).Warn("could not write")
 			}
 		})
 	}
+	for path, file := range this.goFormat.created {
+		log := this.log.WithField("path", filepath.Join("/", path))
+		path, file := path, file
+
+		limiter.Run(func() {
+			if err := this.filesystem.MkdirAll(path, os.FileMode(0755)); err != nil {
+				log.WithError(err).Warn("could not create directory")
+				return
+			}
+
+			if err := this.please.Write(file); err != nil {
+				log.WithError(err).Warn("could not write")
+			}
+		})
+	}
+}
+
+// collectVisibility records the visibility of every rule in the parsed
+// directories before any of them are formatted. Rules which neither set their
+// visibility nor have a package default visibility are not recorded since
+// build definitions such as go_get may provide their own default visibility.
+func (this *Service) collectVisibility() {
+	for path, dir := range this.goFormat.directories {
+		if dir.Build == nil {
+			continue
+		}
+
+		defaults, hasDefaults := packageDefaultVisibility(dir.Build)
+
+		dir.Build.GetRules(func(rule please.Rule) {
+			name := rule.Name()
+			if name == "" {
+				return
+			}
+
+			visibility := defaults
+			if rule.Attr("visibility") != nil {
+				visibility = rule.AttrStrings("visibility")
+			} else if !hasDefaults {
+				return
+			}
+
+			this.goFormat.visibility[path+":"+name] = visibility
+		})
+	}
+}
+
+// checkDepsVisibility determines if every resolved dependency is visible to
+// the directory. Dependencies which are not visible are reported unless gofmt
+// is configured to widen their visibility.
+func (this *Service) checkDepsVisibility(log logging.Logger, config wollemi.Config, dir *Directory, deps []string) bool {
+	ok := true
+
+	for _, dep := range deps {
+		target := please.Split(dep)
+		if target.Path == "" || target.Path == dir.Path {
+			continue
+		}
+
+		label := target.Path + ":" + target.Name
+
+		visibility, found := this.goFormat.visibility[label]
+		if !found || visibilityGrants(visibility, dir.Path) {
+			continue
+		}
+
+		if config.Gofmt.GetWidenVisibility() {
+			this.goFormat.widenMu.Lock()
+			this.goFormat.widen[label] = appendUniqString(this.goFormat.widen[label], dir.Path)
+			this.goFormat.widenMu.Unlock()
+
+			continue
+		}
+
+		log.WithField("dep", "//"+label).
+			WithField("visibility", visibility).
+			Error("dependency not visible")
+
+		ok = false
+	}
+
+	return ok
+}
+
+// widenVisibility adds the packages recorded while formatting to the
+// visibility of the rules they depend on. The paths of the directories whose
+// build files were changed are returned.
+func (this *Service) widenVisibility() map[string]bool {
+	widened := make(map[string]bool)
+
+	for label, pkgs := range this.goFormat.widen {
+		target := please.Split(label)
+
+		dir, ok := this.goFormat.directories[target.Path]
+		if !ok || dir.Build == nil {
+			continue
+		}
+
+		rule := dir.Build.GetRule(target.Name)
+		if rule == nil {
+			continue
+		}
+
+		visibility := append([]string(nil), this.goFormat.visibility[label]...)
+
+		for _, pkg := range pkgs {
+			if pkg == "." {
+				visibility = appendUniqString(visibility, "//...")
+			} else {
+				visibility = appendUniqString(visibility, fmt.Sprintf("//%s/...", pkg))
+			}
+		}
+
+		rule.SetAttr("visibility", please.Strings(visibility...))
+
+		this.log.WithField("path", filepath.Join("/", target.Path)).
+			WithField("rule", target.Name).
+			WithField("visibility", visibility).
+			Info("widened visibility")
+
+		widened[target.Path] = true
+	}
+
+	return widened
+}
+
+// inferTestOnly marks the libraries in the run paths as test only when every
+// rule depending on them is a test or test only itself. Dependents outside of
+// the run paths are taken from the please build graph whereas dependents in
+// the run paths are taken from their formatted rules.
+func (this *Service) inferTestOnly() {
+	var infer bool
+
+	for _, dir := range this.goFormat.directories {
+		if !dir.InRunPath {
+			continue
+		}
+
+		if config := this.filesystem.Config(dir.Path).Merge(this.config); config.Gofmt.GetInferTestOnly() {
+			infer = true
+			break
+		}
+	}
+
+	if !infer {
+		return
+	}
+
+	graph, err := this.please.Graph()
+	if err != nil {
+		this.log.WithError(err).Warn("could not infer test only")
+		return
+	}
+
+	testOnly := make(map[string]bool)
+	revdeps := make(map[string][]string)
+
+	for path, pkg := range graph.Packages {
+		if dir, ok := this.goFormat.directories[path]; ok && dir.InRunPath {
+			continue
+		}
+
+		for name, target := range pkg.Targets {
+			rule := path + ":" + parentRuleName(name)
+
+			if name == parentRuleName(name) {
+				testOnly[rule] = target.Test || target.TestOnly
+			}
+
+			for _, dep := range target.Deps {
+				dep := please.Split(dep)
+				dep.Name = parentRuleName(dep.Name)
+
+				if label := dep.Path + ":" + dep.Name; label != rule {
+					revdeps[label] = appendUniqString(revdeps[label], rule)
+				}
+			}
+		}
+	}
+
+	for path, dir := range this.goFormat.directories {
+		if !dir.InRunPath || dir.Build == nil {
+			continue
+		}
+
+		config := this.filesystem.Config(dir.Path).Merge(this.config)
+
+		dir.Build.GetRules(func(rule please.Rule) {
+			name := rule.Name()
+			if name == "" {
+				return
+			}
+
+			label := path + ":" + name
+
+			testOnly[label] = isTestRule(config, rule)
+
+			for _, dep := range rule.AttrStrings("deps") {
+				target := please.Split(dep)
+				if target.Path == "" {
+					target.Path = path
+				}
+
+				if dep := target.Path + ":" + target.Name; dep != label {
+					revdeps[dep] = appendUniqString(revdeps[dep], label)
+				}
+			}
+		})
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for path, dir := range this.goFormat.directories {
+			if !dir.InRunPath || !dir.Ok || !dir.Rewrite || dir.Build == nil {
+				continue
+			}
+
+			config := this.filesystem.Config(dir.Path).Merge(this.config)
+			if !config.Gofmt.GetInferTestOnly() {
+				continue
+			}
+
+			dir.Build.GetRules(func(rule please.Rule) {
+				if rule.Name() == "" {
+					return
+				}
+
+				label := path + ":" + rule.Name()
+
+				if testOnly[label] || config.Gofmt.GetMapped(rule.Kind()) != "go_library" {
+					return
+				}
+
+				if rule.Attr("test_only") != nil {
+					return // An explicit test_only, even when false, is never inferred.
+				}
+
+				dependents := revdeps[label]
+				if len(dependents) == 0 {
+					return
+				}
+
+				for _, dependent := range dependents {
+					if !testOnly[dependent] {
+						return
+					}
+				}
+
+				rule.SetAttr("test_only", &please.Ident{Name: "True"})
+				testOnly[label] = true
+				changed = true
+
+				this.log.WithField("path", filepath.Join("/", path)).
+					WithField("rule", rule.Name()).
+					WithField("dependents", dependents).
+					Info("inferred test only")
+			})
+		}
+	}
+}
+
+// checkTestOnlyDeps reports the rules in the run paths which are not tests but
+// depend on test only rules of the parsed directories.
+func (this *Service) checkTestOnlyDeps() {
+	testOnly := make(map[string]bool)
+
+	for path, dir := range this.goFormat.directories {
+		if dir.Build == nil {
+			continue
+		}
+
+		dir.Build.GetRules(func(rule please.Rule) {
+			if rule.AttrLiteral("test_only") == "True" {
+				testOnly[path+":"+rule.Name()] = true
+			}
+		})
+	}
+
+	if len(testOnly) == 0 {
+		return
+	}
+
+	for path, dir := range this.goFormat.directories {
+		if !dir.InRunPath || dir.Build == nil {
+			continue
+		}
+
+		config := this.filesystem.Config(dir.Path).Merge(this.config)
+
+		dir.Build.GetRules(func(rule please.Rule) {
+			if isTestRule(config, rule) {
+				return
+			}
+
+			for _, dep := range rule.AttrStrings("deps") {
+				target := please.Split(dep)
+				if target.Path == "" {
+					target.Path = path
+				}
+
+				if testOnly[target.Path+":"+target.Name] {
+					this.log.WithField("path", filepath.Join("/", path)).
+						WithField("rule", rule.Name()).
+						WithField("dep", target.String()).
+						Error("non test rule depends on test only target")
+				}
+			}
+		})
+	}
+}
+
+// isTestRule determines if the rule is a test or is only visible to tests.
+func isTestRule(config wollemi.Config, rule please.Rule) bool {
+	return strings.HasSuffix(config.Gofmt.GetMapped(rule.Kind()), "_test") ||
+		rule.AttrLiteral("test_only") == "True"
+}
+
+// parentRuleName returns the name of the rule which created a hidden rule such
+// as _name#lib, or the name itself when it is not hidden.
+func parentRuleName(name string) string {
+	if !strings.HasPrefix(name, "_") {
+		return name
+	}
+
+	name = strings.TrimPrefix(name, "_")
+
+	if n := strings.Index(name, "#"); n >= 0 {
+		name = name[:n]
+	}
+
+	return name
+}
+
+// visibilityGrants determines if the visibility grants access to the package.
+func visibilityGrants(visibility []string, pkg string) bool {
+	for _, x := range visibility {
+		if x == "PUBLIC" {
+			return true
+		}
+
+		target := please.Split(x)
+
+		switch target.Name {
+		case "...":
+			if target.Path == "." || pkg == target.Path || strings.HasPrefix(pkg, target.Path+"/") {
+				return true
+			}
+		case "__subpackages__":
+			if pkg == target.Path || strings.HasPrefix(pkg, target.Path+"/") {
+				return true
+			}
+		default:
+			if pkg == target.Path {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func (this *Service) Format(config wollemi.Config, paths []string) error {
@@ -451,7 +978,37 @@ func (this *Service) getRuleDeps(files []string, config wollemi.Config, dir *Dir
 	return resolved, unresolved, nil
 }
 
+// packageDefaultVisibility returns the default visibility given to rules by the
+// package call of the build file and false when there is none.
+func packageDefaultVisibility(file please.File) ([]string, bool) {
+	var visibility []string
+	var ok bool
+
+	file.GetRules(func(rule please.Rule) {
+		if rule.Kind() == "package" && rule.Attr("default_visibility") != nil {
+			visibility = rule.AttrStrings("default_visibility")
+			ok = true
+		}
+	})
+
+	return visibility, ok
+}
+
 func (this *Service) getVisibility(config wollemi.Config, path string) string {
+	visibility := this.getDefaultVisibility(config, path)
+
+	// Go only allows packages under an internal directory to be imported from
+	// within the parent of the internal directory.
+	if internal := goInternalVisibility(path); internal != "" {
+		if !visibilityWithin(visibility, internal) {
+			visibility = internal
+		}
+	}
+
+	return visibility
+}
+
+func (this *Service) getDefaultVisibility(config wollemi.Config, path string) string {
 	if config.DefaultVisibility != "" {
 		return config.DefaultVisibility
 	}
@@ -473,6 +1030,78 @@ func (this *Service) getVisibility(config wollemi.Config, path string) string {
 	return "PUBLIC"
 }
 
+// goInternalVisibility returns the visibility which matches the go internal
+// package boundary of the directory or an empty string when the directory is
+// not under an internal directory.
+func goInternalVisibility(path string) string {
+	chunks := strings.Split(path, "/")
+
+	for i := len(chunks) - 1; i >= 0; i-- {
+		if chunks[i] == "internal" {
+			if i == 0 {
+				return "//..."
+			}
+
+			return fmt.Sprintf("//%s/...", strings.Join(chunks[:i], "/"))
+		}
+	}
+
+	return ""
+}
+
+// visibilityWithin determines if the visibility grants nothing beyond the
+// boundary visibility, which is either //... or a //path/... visibility.
+func visibilityWithin(visibility, boundary string) bool {
+	if boundary == "//..." {
+		return visibility != "PUBLIC"
+	}
+
+	if visibility == "PUBLIC" || visibility == "//..." {
+		return false
+	}
+
+	path := please.Split(visibility).Path
+	base := please.Split(boundary).Path
+
+	return path == base || strings.HasPrefix(path, base+"/")
+}
+
+// visibilitiesWithin determines if every visibility is within the boundary
+// visibility. Every visibility is within an empty boundary.
+func visibilitiesWithin(visibilities []string, boundary string) bool {
+	if boundary == "" {
+		return true
+	}
+
+	for _, visibility := range visibilities {
+		if !visibilityWithin(visibility, boundary) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkInternalVisibility warns when the go_library rule is visible beyond the
+// go internal package boundary of its directory.
+func checkInternalVisibility(log logging.Logger, dir *Directory, rule please.Rule) {
+	internal := goInternalVisibility(dir.Path)
+	if internal == "" {
+		return
+	}
+
+	visibility := rule.AttrStrings("visibility")
+	if rule.Attr("visibility") == nil {
+		visibility, _ = packageDefaultVisibility(dir.Build)
+	}
+
+	if !visibilitiesWithin(visibility, internal) {
+		log.WithField("visibility", visibility).
+			WithField("allowed", internal).
+			Warn("visibility wider than go internal package allows")
+	}
+}
+
 func (this *Service) getRuleSrcs(dir *Directory, config wollemi.Config, srcFiles []string) []string {
 	srcs := make([]string, 0, len(srcFiles))
 
@@ -531,6 +1160,12 @@ func (this *Service) formatDir(log logging.Logger, dir *Directory) {
 
 	sortManagedRules(config, managed)
 
+	for _, rule := range managed {
+		if config.Gofmt.GetMapped(rule.Kind()) == "go_library" {
+			checkInternalVisibility(log.WithField("rule", rule.Name()), dir, rule)
+		}
+	}
+
 	// ---------------------------------------------------------------------------
 	// Manage existing go rules in this directory.
 
@@ -712,6 +1347,10 @@ ManageRules:
 				return
 			}
 
+			if !this.checkDepsVisibility(log, config, dir, resolved) {
+				return
+			}
+
 			if isExplicitSources || config.ExplicitSources.IsTrue() {
 				srcs := this.getRuleSrcs(dir, config, srcFiles)
 				rule.SetAttr("srcs", please.Strings(srcs...))
@@ -764,7 +1403,7 @@ CreateRules:
 
 			rule = this.please.NewRule(
 				config.Gofmt.GetMapped(x.Kind),
-				filepath.Base(filepath.Join(this.wd, dir.Path)),
+				config.Gofmt.GetName(x.Kind, filepath.Base(filepath.Join(this.wd, dir.Path)), dir.Gopkg.Name),
 			)
 
 			include, exclude = []string{"*.go"}, []string{"*_test.go"}
@@ -794,13 +1433,15 @@ CreateRules:
 			}
 
 			exclude = dir.Gopkg.XTestGoFiles
-			name := "test"
+			naming := "go_test"
 
 			if len(dir.Gopkg.XTestGoFiles) > 0 {
 				config.ExplicitSources = optional.BoolValue(true)
-				name = "internal_test"
+				naming = "internal_test"
 			}
 
+			name := config.Gofmt.GetName(naming, filepath.Base(filepath.Join(this.wd, dir.Path)), dir.Gopkg.Name)
+
 			rule = this.please.NewRule(config.Gofmt.GetMapped(x.Kind), name)
 		case x.Kind == "go_test" && x.External == true:
 			if len(dir.Gopkg.XTestGoFiles) == 0 {
@@ -810,13 +1451,15 @@ CreateRules:
 			pkgFiles = dir.Gopkg.XTestGoFiles
 			include = []string{"*_test.go"}
 			exclude = dir.Gopkg.TestGoFiles
-			name := "test"
+			naming := "go_test"
 
 			if len(dir.Gopkg.TestGoFiles) > 0 {
 				config.ExplicitSources = optional.BoolValue(true)
-				name = "external_test"
+				naming = "external_test"
 			}
 
+			name := config.Gofmt.GetName(naming, filepath.Base(filepath.Join(this.wd, dir.Path)), dir.Gopkg.Name)
+
 			rule = this.please.NewRule(config.Gofmt.GetMapped(x.Kind), name)
 		}
 
@@ -853,6 +1496,15 @@ CreateRules:
 			continue
 		}
 
+		if other := dir.Build.GetRule(rule.Name()); other != nil {
+			log.WithFields(logging.Fields{
+				"kind":   other.Kind(),
+				"reason": "name collides with existing rule",
+			}).Warn("skipped")
+
+			continue
+		}
+
 		if config.ExplicitSources.IsTrue() {
 			rule.SetAttr("srcs", please.Strings(srcs...))
 		} else {
@@ -863,10 +1515,27 @@ CreateRules:
 			rule.SetAttr("external", &please.Ident{Name: "True"})
 		}
 
+		applyRuleTemplate(log, rule, config.Gofmt.GetTemplate(x.Kind))
+
 		if x.Kind == "go_binary" || x.Kind == "go_library" {
-			visibility := this.getVisibility(config, dir.Path)
+			if rule.Attr("visibility") == nil {
+				visibility := this.getVisibility(config, dir.Path)
+
+				// The package default visibility applies unless a different default
+				// visibility was explicitly configured or the package default is wider
+				// than go allows for internal packages.
+				if defaults, ok := packageDefaultVisibility(dir.Build); ok {
+					within := visibilitiesWithin(defaults, goInternalVisibility(dir.Path))
 
-			rule.SetAttr("visibility", please.Strings(visibility))
+					if (config.DefaultVisibility == "" && within) || inStrings(defaults, visibility) {
+						visibility = ""
+					}
+				}
+
+				if visibility != "" {
+					rule.SetAttr("visibility", please.Strings(visibility))
+				}
+			}
 		}
 
 		resolved, unresolved, err := this.getRuleDeps(pkgFiles, config, dir)
@@ -883,6 +1552,10 @@ CreateRules:
 			return
 		}
 
+		if !this.checkDepsVisibility(log, config, dir, resolved) {
+			return
+		}
+
 		resolved = append(deps, resolved...)
 
 		if len(resolved) > 0 {
@@ -917,6 +1590,86 @@ CreateRules:
 	}
 }
 
+// applyRuleTemplate sets the default attributes of a created rule from its rule
+// kind template. Attributes which gofmt manages itself cannot be templated.
+func applyRuleTemplate(log logging.Logger, rule please.Rule, template map[string]interface{}) {
+	keys := make([]string, 0, len(template))
+	for key := range template {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		log := log.WithField("attr", key)
+
+		switch key {
+		case "name", "srcs", "deps", "external":
+			log.Warn("template attribute is managed by gofmt")
+			continue
+		}
+
+		expr, err := templateExpr(template[key])
+		if err != nil {
+			log.WithError(err).Warn("invalid template attribute")
+			continue
+		}
+
+		rule.SetAttr(key, expr)
+	}
+}
+
+// templateExpr converts a json decoded template value into a build expression.
+func templateExpr(value interface{}) (please.Expr, error) {
+	switch value := value.(type) {
+	case string:
+		return please.String(value), nil
+	case bool:
+		if value {
+			return &please.Ident{Name: "True"}, nil
+		}
+
+		return &please.Ident{Name: "False"}, nil
+	case float64:
+		return &please.LiteralExpr{Token: strconv.FormatFloat(value, 'f', -1, 64)}, nil
+	case []interface{}:
+		list := &please.ListExpr{List: make([]please.Expr, len(value))}
+
+		for i, value := range value {
+			expr, err := templateExpr(value)
+			if err != nil {
+				return nil, err
+			}
+
+			list.List[i] = expr
+		}
+
+		return list, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		dict := &please.DictExpr{List: make([]please.Expr, len(keys))}
+
+		for i, key := range keys {
+			expr, err := templateExpr(value[key])
+			if err != nil {
+				return nil, err
+			}
+
+			dict.List[i] = &please.KeyValueExpr{Key: please.String(key), Value: expr}
+		}
+
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v", value)
+	}
+}
+
 func getSrcFilesFromExpr(expr please.Expr, dir *Directory) []string {
 	var srcFiles []string
 
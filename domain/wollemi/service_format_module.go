@@ -0,0 +1,358 @@
+package wollemi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/tcncloud/wollemi/ports/please"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+// resolveGoModule looks up the module providing an unresolved third party
+// import in the go.sum of the repo and the local module cache, without any
+// network access. The go_module rule of the module is suggested or, when
+// gofmt.fix_unresolved is set, added to the build file of the module under the
+// first third party dir so the import resolves to it. It returns the target
+// the import resolves to, which is empty unless the rule was added.
+func (this *Service) resolveGoModule(config wollemi.Config, pkg string) string {
+	if _, ok := config.KnownDependency[pkg]; ok || this.isInternal(pkg) {
+		return ""
+	}
+
+	fix := config.Gofmt.GetFixUnresolved()
+
+	mod, version, ok := this.lookupGoModule(pkg)
+	if !ok {
+		return ""
+	}
+
+	// A rule for the module already exists but does not install the package.
+	// Rules added for unresolved imports install every package they need
+	// whereas other rules only do when gofmt.install_missing is set.
+	if labels, ok := this.goFormat.modules[mod]; ok {
+		if _, added := this.moduleRule(labels[0]); !fix || !added {
+			return ""
+		}
+
+		this.installMissing(config, pkg)
+
+		target, _ := this.getTargetInternal(config, pkg, false, 0)
+
+		return target
+	}
+
+	thirdPartyDirs := config.GetThirdPartyDirs()
+	if len(thirdPartyDirs) == 0 {
+		return ""
+	}
+
+	path := filepath.Join(thirdPartyDirs[0], mod)
+	name := filepath.Base(mod)
+	label := fmt.Sprintf("//%s:%s", path, name)
+
+	var install []string
+	if pkg != mod {
+		install = []string{strings.TrimPrefix(pkg, mod+"/")}
+	}
+
+	log := this.log.WithField("go_import", pkg).
+		WithField("module", mod).
+		WithField("version", version).
+		WithField("path", filepath.Join("/", path))
+
+	if fix && !this.canAddGoModule(path) {
+		log.Debug("not adding go module rule to build file which was not parsed")
+		fix = false
+	}
+
+	if !fix {
+		if !this.goFormat.suggested[pkg] {
+			this.goFormat.suggested[pkg] = true
+
+			log.WithField("rule", goModuleRule(name, mod, version, install)).
+				Warn("suggested go module rule")
+		}
+
+		return ""
+	}
+
+	if rule, _ := this.moduleRule(label); rule != nil {
+		log.WithField("rule", name).Warn("could not add go module rule with duplicate name")
+		return ""
+	}
+
+	rule := this.please.NewRule("go_module", name)
+
+	if len(install) > 0 {
+		rule.SetAttr("install", please.Strings(install...))
+	}
+
+	rule.SetAttr("module", please.String(mod))
+	rule.SetAttr("version", please.String(version))
+	rule.SetAttr("visibility", please.Strings("PUBLIC"))
+
+	// Parsed build files are shared with the goroutines formatting their
+	// directories so the rule is only added to them once formatting is done.
+	if dir, ok := this.goFormat.directories[path]; ok && dir.Build != nil {
+		this.goFormat.pendingRules[path] = append(this.goFormat.pendingRules[path], rule)
+	} else {
+		file, ok := this.goFormat.created[path]
+		if !ok {
+			file = this.please.NewFile(filepath.Join(path, this.buildFileNames()[0]))
+			this.goFormat.created[path] = file
+		}
+
+		file.AddRule(rule)
+	}
+
+	this.goFormat.modules[mod] = append(this.goFormat.modules[mod], label)
+	this.goFormat.external[pkg] = append(this.goFormat.external[pkg], label)
+
+	log.WithField("rule", name).Info("added go module rule")
+
+	return label
+}
+
+// canAddGoModule determines if a go_module rule can be added to the third party
+// package. Build files which were not parsed, either because the directory is
+// ignored or because it was not walked, are never written so the rule is only
+// suggested for them.
+func (this *Service) canAddGoModule(path string) bool {
+	if dir, ok := this.goFormat.directories[path]; ok && dir.Build != nil {
+		return true
+	}
+
+	if _, ok := this.goFormat.created[path]; ok {
+		return true
+	}
+
+	if this.isIgnored(path) {
+		return false
+	}
+
+	for _, name := range this.buildFileNames() {
+		if _, err := this.filesystem.Stat(filepath.Join(path, name)); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// moduleRule returns the go_module rule of the label and whether it was added
+// for an unresolved import while formatting. Added rules are not yet seen by
+// any other goroutine so unlike the rules of parsed build files they can be
+// changed while formatting.
+func (this *Service) moduleRule(label string) (please.Rule, bool) {
+	target := please.Split(label)
+
+	for _, rule := range this.goFormat.pendingRules[target.Path] {
+		if rule.Name() == target.Name {
+			return rule, true
+		}
+	}
+
+	if file, ok := this.goFormat.created[target.Path]; ok {
+		if rule := file.GetRule(target.Name); rule != nil {
+			return rule, true
+		}
+	}
+
+	if dir, ok := this.goFormat.directories[target.Path]; ok && dir.Build != nil {
+		return dir.Build.GetRule(target.Name), false
+	}
+
+	return nil, false
+}
+
+// applyModuleChanges adds the go_module rules and install entries resolved
+// while formatting to the parsed build files of their third party packages.
+// It runs once formatting is done since those build files are shared with the
+// goroutines formatting their directories.
+func (this *Service) applyModuleChanges() {
+	for path, rules := range this.goFormat.pendingRules {
+		build := this.goFormat.directories[path].Build
+
+		for _, rule := range rules {
+			build.AddRule(rule)
+		}
+
+		this.goFormat.changed[path] = true
+	}
+
+	for label, entries := range this.goFormat.pendingInstall {
+		target := please.Split(label)
+
+		rule := this.goFormat.directories[target.Path].Build.GetRule(target.Name)
+
+		install := rule.AttrStrings("install")
+		if len(install) == 0 {
+			install = []string{"."} // Empty install list installs the root package
+		}
+
+		rule.SetAttr("install", please.Strings(append(install, entries...)...))
+
+		this.goFormat.changed[target.Path] = true
+	}
+}
+
+// lookupGoModule returns the module and version providing the go package. The
+// go.sum of the repo is consulted first, preferring the longest module path
+// and the greatest version, and the module cache is scanned otherwise.
+func (this *Service) lookupGoModule(pkg string) (string, string, bool) {
+	sums := this.goSum()
+
+	for mod := pkg; mod != "." && mod != "/"; mod = filepath.Dir(mod) {
+		version := maxVersion(sums[mod])
+		if version != "" && this.goModuleHasPackage(mod, version, pkg) {
+			return mod, version, true
+		}
+	}
+
+	cache := this.golang.GOMODCACHE()
+	if cache == "" {
+		return "", "", false
+	}
+
+	for mod := pkg; mod != "." && mod != "/"; mod = filepath.Dir(mod) {
+		escaped, err := module.EscapePath(mod)
+		if err != nil {
+			continue
+		}
+
+		infos, err := this.filesystem.ReadDir(filepath.Join(cache, filepath.Dir(escaped)))
+		if err != nil {
+			continue
+		}
+
+		prefix := filepath.Base(escaped) + "@"
+
+		var versions []string
+
+		for _, info := range infos {
+			if !info.IsDir() || !strings.HasPrefix(info.Name(), prefix) {
+				continue
+			}
+
+			version, err := module.UnescapeVersion(strings.TrimPrefix(info.Name(), prefix))
+			if err == nil && semver.IsValid(version) {
+				versions = append(versions, version)
+			}
+		}
+
+		version := maxVersion(versions)
+		if version != "" && this.goModuleHasPackage(mod, version, pkg) {
+			return mod, version, true
+		}
+	}
+
+	return "", "", false
+}
+
+// goModuleHasPackage determines if the module version contains the go package.
+// When the module version is missing from the module cache it is assumed to.
+func (this *Service) goModuleHasPackage(mod, version, pkg string) bool {
+	cache := this.golang.GOMODCACHE()
+	if cache == "" {
+		return true
+	}
+
+	escaped, err := module.EscapePath(mod)
+	if err != nil {
+		return false
+	}
+
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return false
+	}
+
+	root := filepath.Join(cache, escaped+"@"+escapedVersion)
+
+	if _, err := this.filesystem.Stat(root); err != nil {
+		return true
+	}
+
+	_, err = this.filesystem.Stat(filepath.Join(root, strings.TrimPrefix(pkg, mod)))
+
+	return err == nil
+}
+
+// goSum returns the versions of every module in the go.sum of the repo whose
+// sources were downloaded, reading it on first use.
+func (this *Service) goSum() map[string][]string {
+	if this.goFormat.goSum != nil {
+		return this.goFormat.goSum
+	}
+
+	this.goFormat.goSum = map[string][]string{}
+
+	buf := bytes.NewBuffer(nil)
+
+	if err := this.filesystem.ReadAll(buf, "go.sum"); err != nil {
+		if !os.IsNotExist(err) {
+			this.log.WithError(err).Warn("could not read go.sum")
+		}
+
+		return this.goFormat.goSum
+	}
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+
+		this.goFormat.goSum[fields[0]] = appendUniqString(this.goFormat.goSum[fields[0]], fields[1])
+	}
+
+	return this.goFormat.goSum
+}
+
+// maxVersion returns the greatest of the semantic versions, ignoring any other
+// versions such as commit hashes.
+func maxVersion(versions []string) string {
+	var max string
+
+	for _, version := range versions {
+		if !semver.IsValid(version) {
+			continue
+		}
+
+		if max == "" || semver.Compare(version, max) > 0 {
+			max = version
+		}
+	}
+
+	return max
+}
+
+// goModuleRule returns a ready to paste go_module rule.
+func goModuleRule(name, mod, version string, install []string) string {
+	attrs := []string{fmt.Sprintf("name = %q", name)}
+
+	if len(install) > 0 {
+		quoted := make([]string, len(install))
+		for i, entry := range install {
+			quoted[i] = fmt.Sprintf("%q", entry)
+		}
+
+		attrs = append(attrs, fmt.Sprintf("install = [%s]", strings.Join(quoted, ", ")))
+	}
+
+	attrs = append(attrs,
+		fmt.Sprintf("module = %q", mod),
+		fmt.Sprintf("version = %q", version),
+		`visibility = ["PUBLIC"]`,
+	)
+
+	return fmt.Sprintf("go_module(%s)", strings.Join(attrs, ", "))
+}
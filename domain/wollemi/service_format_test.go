@@ -2,6 +2,7 @@ package wollemi_test
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,6 +17,7 @@ import (
 	"github.com/tcncloud/wollemi/domain/optional"
 	"github.com/tcncloud/wollemi/domain/wollemi"
 	"github.com/tcncloud/wollemi/ports/golang"
+	wollemiport "github.com/tcncloud/wollemi/ports/wollemi"
 	"github.com/tcncloud/wollemi/testdata/expect"
 	"github.com/tcncloud/wollemi/testdata/please"
 )
@@ -180,6 +182,68 @@ func (t *ServiceSuite) TestService_GoFormat() {
 				},
 			},
 		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "applies rule templates to created rules",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"integration/server"},
+			Config: map[string]wollemi.Config{
+				"integration/server": {
+					Gofmt: wollemi.Gofmt{
+						Template: map[string]map[string]interface{}{
+							"go_library": {
+								"test_only":  true,
+								"visibility": []interface{}{"//integration/..."},
+								"deps":       []interface{}{"//ignored"},
+							},
+							"go_test": {
+								"labels": []interface{}{"integration"},
+								"size":   "large",
+							},
+						},
+					},
+				},
+			},
+			Parse: t.WithThirdPartyGo(nil),
+			ImportDir: map[string]*golang.Package{
+				"integration/server": &golang.Package{
+					GoFiles:     []string{"server.go"},
+					TestGoFiles: []string{"server_test.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+						"server_test.go": []string{
+							"github.com/stretchr/testify/assert",
+							"testing",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"integration/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "test_only", true),
+							please.NewAssignExpr("=", "visibility", []string{"//integration/..."}),
+						}),
+						please.NewCallExpr("go_test", []please.Expr{
+							please.NewAssignExpr("=", "name", "test"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*_test.go"})),
+							please.NewAssignExpr("=", "labels", []string{"integration"}),
+							please.NewAssignExpr("=", "size", "large"),
+							please.NewAssignExpr("=", "deps", []string{
+								":server",
+								"//third_party/go/github.com/stretchr:testify",
+							}),
+						}),
+					},
+				},
+			},
+		},
 	}, { // TEST_CASE ------------------------------------------------------------
 		Title: "creates missing go rules across multiple directories",
 		Data: &GoFormatTestData{
@@ -305,31 +369,826 @@ func (t *ServiceSuite) TestService_GoFormat() {
 						}),
 					},
 				},
-				"app/protos/BUILD.plz": &please.BuildFile{
-					Stmt: []please.Expr{
-						please.NewCallExpr("grpc_library", []please.Expr{
-							please.NewAssignExpr("=", "name", "protos"),
-							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.proto"})),
-							please.NewAssignExpr("=", "protoc_flags", []string{
-								"-I third_party/proto",
-								"-I .",
-							}),
-							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
-							please.NewAssignExpr("=", "labels", []string{"link:app/protos"}),
-						}),
-						please.NewCallExpr("go_mock", []please.Expr{
-							please.NewAssignExpr("=", "name", "mock"),
-							please.NewAssignExpr("=", "package", "github.com/example/app/protos"),
-							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
-							please.NewAssignExpr("=", "deps", []string{
-								":protos",
-								"//third_party/go/github.com/golang:mock",
-								"//third_party/go/google.golang.org:grpc",
-							}),
-						}),
-					},
+				"app/protos/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("grpc_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "protos"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.proto"})),
+							please.NewAssignExpr("=", "protoc_flags", []string{
+								"-I third_party/proto",
+								"-I .",
+							}),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+							please.NewAssignExpr("=", "labels", []string{"link:app/protos"}),
+						}),
+						please.NewCallExpr("go_mock", []please.Expr{
+							please.NewAssignExpr("=", "name", "mock"),
+							please.NewAssignExpr("=", "package", "github.com/example/app/protos"),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+							please.NewAssignExpr("=", "deps", []string{
+								":protos",
+								"//third_party/go/github.com/golang:mock",
+								"//third_party/go/google.golang.org:grpc",
+							}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "names created rules using configured naming",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/..."},
+			Config: map[string]wollemi.Config{
+				"app": wollemi.Config{
+					Gofmt: wollemi.Gofmt{
+						Naming: wollemi.Naming{
+							GoBinary:  "{dir}_bin",
+							GoLibrary: "{pkg}_lib",
+							GoTest:    "{pkg}_test",
+						},
+					},
+				},
+				"app/server": wollemi.Config{
+					Gofmt: wollemi.Gofmt{
+						Naming: wollemi.Naming{
+							GoBinary:  "{dir}_bin",
+							GoLibrary: "{pkg}_lib",
+							GoTest:    "{pkg}_test",
+						},
+					},
+				},
+			},
+			Parse: t.WithThirdPartyGo(nil),
+			ImportDir: map[string]*golang.Package{
+				"app": &golang.Package{
+					Name:    "main",
+					GoFiles: []string{"main.go"},
+					GoFileImports: map[string][]string{
+						"main.go": []string{
+							"github.com/example/app/server",
+						},
+					},
+				},
+				"app/server": &golang.Package{
+					Name:        "routes",
+					GoFiles:     []string{"server.go"},
+					TestGoFiles: []string{"server_test.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+						"server_test.go": []string{
+							"testing",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_binary", []please.Expr{
+							please.NewAssignExpr("=", "name", "app_bin"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+							please.NewAssignExpr("=", "deps", []string{
+								"//app/server:routes_lib",
+							}),
+						}),
+					},
+				},
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "routes_lib"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+						}),
+						please.NewCallExpr("go_test", []please.Expr{
+							please.NewAssignExpr("=", "name", "routes_test"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*_test.go"})),
+							please.NewAssignExpr("=", "deps", []string{
+								":routes_lib",
+							}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "does not create go rules whose name collides with an existing rule",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("filegroup", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", []string{"config.yaml"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("filegroup", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", []string{"config.yaml"}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "uses build file names configured by please",
+		Data: &GoFormatTestData{
+			Gosrc:        gosrc,
+			Gopkg:        gopkg,
+			Paths:        []string{"app/..."},
+			PleaseConfig: withBuildFileName("BUILD.build", "BUILD.plz"),
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/server/BUILD.build": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app": &golang.Package{
+					Name:    "main",
+					GoFiles: []string{"main.go"},
+					GoFileImports: map[string][]string{
+						"main.go": []string{
+							"github.com/example/app/server",
+						},
+					},
+				},
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/BUILD.build": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_binary", []please.Expr{
+							please.NewAssignExpr("=", "name", "app"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+							please.NewAssignExpr("=", "deps", []string{
+								"//app/server",
+							}),
+						}),
+					},
+				},
+				"app/server/BUILD.build": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "omits visibility provided by the package default visibility",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "omits configured default visibility provided by the package default visibility",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Config: map[string]wollemi.Config{
+				"app/server": wollemi.Config{
+					DefaultVisibility: "PUBLIC",
+				},
+			},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "writes configured default visibility which differs from the package default visibility",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Config: map[string]wollemi.Config{
+				"app/server": wollemi.Config{
+					DefaultVisibility: "//app/...",
+				},
+			},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("package", []please.Expr{
+							please.NewAssignExpr("=", "default_visibility", []string{"PUBLIC"}),
+						}),
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "restricts visibility of created rules under go internal directories",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/internal/db"},
+			Config: map[string]wollemi.Config{
+				"app/internal/db": wollemi.Config{
+					DefaultVisibility: "PUBLIC",
+				},
+			},
+			Parse: t.WithThirdPartyGo(nil),
+			ImportDir: map[string]*golang.Package{
+				"app/internal/db": &golang.Package{
+					GoFiles: []string{"db.go"},
+					GoFileImports: map[string][]string{
+						"db.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/internal/db/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "widens visibility of dependencies when configured",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				WidenVisibility: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"lib/db/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//lib/..."}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/example/lib/db",
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "deps", []string{"//lib/db"}),
+						}),
+					},
+				},
+				"lib/db/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//lib/...", "//app/server/..."}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "infers test only for libraries depended on only by tests",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				InferTestOnly: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"lib/fake", "lib/real"},
+			Graph: &please.Graph{
+				Packages: map[string]*please.GraphPackage{
+					"app/server": &please.GraphPackage{
+						Targets: map[string]*please.GraphTarget{
+							"server": &please.GraphTarget{
+								Deps: []string{"//lib/real:real"},
+							},
+							"_test#lib": &please.GraphTarget{
+								Deps: []string{"//app/server:server", "//lib/fake:fake"},
+							},
+							"test": &please.GraphTarget{
+								Deps: []string{"//app/server:_test#lib"},
+								Test: true,
+							},
+						},
+					},
+				},
+			},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"lib/fake/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "fake"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+				"lib/real/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "real"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"lib/fake": &golang.Package{
+					GoFiles: []string{"fake.go"},
+					GoFileImports: map[string][]string{
+						"fake.go": []string{
+							"strings",
+						},
+					},
+				},
+				"lib/real": &golang.Package{
+					GoFiles: []string{"real.go"},
+					GoFileImports: map[string][]string{
+						"real.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"lib/fake/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "fake"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "test_only", &please.Ident{Name: "True"}),
+						}),
+					},
+				},
+				"lib/real/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "real"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "does not infer test only for libraries which explicitly set it",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				InferTestOnly: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"lib/fake", "lib/real"},
+			Graph: &please.Graph{
+				Packages: map[string]*please.GraphPackage{
+					"app/server": &please.GraphPackage{
+						Targets: map[string]*please.GraphTarget{
+							"server": &please.GraphTarget{
+								Deps: []string{"//lib/real:real"},
+							},
+							"_test#lib": &please.GraphTarget{
+								Deps: []string{"//app/server:server", "//lib/fake:fake"},
+							},
+							"test": &please.GraphTarget{
+								Deps: []string{"//app/server:_test#lib"},
+								Test: true,
+							},
+						},
+					},
+				},
+			},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"lib/fake/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "fake"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "test_only", &please.Ident{Name: "False"}),
+						}),
+					},
+				},
+				"lib/real/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "real"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"lib/fake": &golang.Package{
+					GoFiles: []string{"fake.go"},
+					GoFileImports: map[string][]string{
+						"fake.go": []string{
+							"strings",
+						},
+					},
+				},
+				"lib/real": &golang.Package{
+					GoFiles: []string{"real.go"},
+					GoFileImports: map[string][]string{
+						"real.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"lib/fake/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "fake"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "test_only", &please.Ident{Name: "False"}),
+						}),
+					},
+				},
+				"lib/real/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "real"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "installs missing go module packages when configured",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				InstallMissing: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGoModules(nil),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/spf13/cobra/doc",
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "deps", []string{"//third_party/go:github.com__spf13__cobra"}),
+						}),
+					},
+				},
+				"third_party/go/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "github.com__spf13__cobra"),
+							please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+							please.NewAssignExpr("=", "install", []string{".", "doc"}),
+							please.NewAssignExpr("=", "version", "v1.0.0"),
+						}),
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "github.com__spf13__pflag"),
+							please.NewAssignExpr("=", "module", "github.com/spf13/pflag"),
+							please.NewAssignExpr("=", "install", []string{"."}),
+							please.NewAssignExpr("=", "version", "v1.0.5"),
+						}),
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "github.com__golang__protobuf"),
+							please.NewAssignExpr("=", "module", "github.com/golang/protobuf"),
+							please.NewAssignExpr("=", "install", []string{"proto/ptypes/wrappers"}),
+							please.NewAssignExpr("=", "version", "v1.3.2"),
+						}),
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "github.com__golang__mock"),
+							please.NewAssignExpr("=", "module", "github.com/golang/mock"),
+							please.NewAssignExpr("=", "version", "v1.3.2"),
+							please.NewAssignExpr("=", "install", []string{
+								"mockgen/model",
+								"gomock",
+							}),
+						}),
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "github.com__stretchr__testify"),
+							please.NewAssignExpr("=", "module", "github.com/stretchr/testify"),
+							please.NewAssignExpr("=", "version", "v1.4.0"),
+							please.NewAssignExpr("=", "install", []string{
+								"assert",
+								"require",
+								"vendor/github.com/davecgh/go-spew/spew",
+								"vendor/github.com/pmezard/go-difflib/difflib",
+							}),
+						}),
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "google.golang.org__grpc"),
+							please.NewAssignExpr("=", "module", "google.golang.org/grpc"),
+							please.NewAssignExpr("=", "install", []string{".", "credentials"}),
+							please.NewAssignExpr("=", "version", "v1.26.0"),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "writes go module rules for unresolved imports when configured",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				FixUnresolved: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGoModules(nil),
+			GoSum: strings.Join([]string{
+				"github.com/olivere/elastic v1.2.0 h1:aaa=",
+				"github.com/olivere/elastic v1.2.0/go.mod h1:bbb=",
+				"github.com/olivere/elastic v1.10.0 h1:ccc=",
+				"github.com/olivere/elastic v1.10.0/go.mod h1:ddd=",
+				"github.com/olivere/elastic v1.11.0/go.mod h1:eee=",
+			}, "\n"),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/olivere/elastic/config",
+							"github.com/olivere/elastic/uritemplates",
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "deps", []string{"//third_party/go/github.com/olivere/elastic"}),
+						}),
+					},
+				},
+				"third_party/go/github.com/olivere/elastic/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_module", []please.Expr{
+							please.NewAssignExpr("=", "name", "elastic"),
+							please.NewAssignExpr("=", "install", []string{"config", "uritemplates"}),
+							please.NewAssignExpr("=", "module", "github.com/olivere/elastic"),
+							please.NewAssignExpr("=", "version", "v1.10.0"),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "does not write go module rules into ignored directories",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				FixUnresolved: wollemiport.Bool(true),
+			},
+			Ignore: []string{"/third_party/go/github.com/olivere/"},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGoModules(nil),
+			GoSum: strings.Join([]string{
+				"github.com/olivere/elastic v1.2.0 h1:aaa=",
+				"github.com/olivere/elastic v1.2.0/go.mod h1:bbb=",
+				"github.com/olivere/elastic v1.10.0 h1:ccc=",
+				"github.com/olivere/elastic v1.10.0/go.mod h1:ddd=",
+				"github.com/olivere/elastic v1.11.0/go.mod h1:eee=",
+			}, "\n"),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/olivere/elastic/config",
+							"github.com/olivere/elastic/uritemplates",
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{},
+			},
+		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "does not write go module rules into build files which were not parsed",
+		Config: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				FixUnresolved: wollemiport.Bool(true),
+			},
+		},
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGoModules(map[string]*please.BuildFile{
+				"third_party/go/github.com/olivere/elastic/BUILD.plz": nil,
+			}),
+			ParseErr: map[string]error{
+				"third_party/go/github.com/olivere/elastic/BUILD.plz": fmt.Errorf("syntax error"),
+			},
+			GoSum: strings.Join([]string{
+				"github.com/olivere/elastic v1.2.0 h1:aaa=",
+				"github.com/olivere/elastic v1.2.0/go.mod h1:bbb=",
+				"github.com/olivere/elastic v1.10.0 h1:ccc=",
+				"github.com/olivere/elastic v1.10.0/go.mod h1:ddd=",
+				"github.com/olivere/elastic v1.11.0/go.mod h1:eee=",
+			}, "\n"),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/olivere/elastic/config",
+							"github.com/olivere/elastic/uritemplates",
+							"strings",
+						},
+					},
+				},
+			},
+			Stat: map[string]*FileInfo{
+				"third_party/go/github.com/olivere/elastic/BUILD.plz": &FileInfo{
+					FileName: "BUILD.plz",
+					FileMode: os.FileMode(420),
 				},
 			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{},
+			},
 		},
 	}, { // TEST_CASE ------------------------------------------------------------
 		Title: "manages existing go_library rules",
@@ -1232,6 +2091,61 @@ func (t *ServiceSuite) TestService_GoFormat() {
 				},
 			},
 		},
+	}, { // TEST_CASE ------------------------------------------------------------
+		Title: "resolves third party dependencies from configured third party dirs",
+		Data: &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Config: map[string]wollemi.Config{
+				".": {ThirdPartyDirs: []string{"vendor/go", "third_party/go"}},
+			},
+			Parse: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", []string{"server.go"}),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+				"vendor/go/github.com/spf13/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_get", []please.Expr{
+							please.NewAssignExpr("=", "name", "cobra"),
+							please.NewAssignExpr("=", "get", "github.com/spf13/cobra"),
+							please.NewAssignExpr("=", "revision", "v1.0.0"),
+						}),
+					},
+				},
+			},
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/spf13/cobra",
+							"strings",
+						},
+					},
+				},
+			},
+			Write: map[string]*please.BuildFile{
+				"app/server/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "server"),
+							please.NewAssignExpr("=", "srcs", []string{"server.go"}),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "deps", []string{
+								"//vendor/go/github.com/spf13:cobra",
+							}),
+						}),
+					},
+				},
+			},
+		},
 	}, { // TEST_CASE ------------------------------------------------------------
 		Title: "deletes go rule deps attribute when no dependencies required",
 		Data: &GoFormatTestData{
@@ -1730,6 +2644,284 @@ func (t *ServiceSuite) TestService_GoFormat() {
 		})
 	}
 
+	t.It("warns about go_library rules visible beyond their go internal boundary", func(t *T) {
+		data := &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/internal/db"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"app/internal/db/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/internal/db": &golang.Package{
+					GoFiles: []string{"db.go"},
+					GoFileImports: map[string][]string{
+						"db.go": []string{
+							"strings",
+						},
+					},
+				},
+			},
+		}
+
+		write := make(chan please.File, 10)
+
+		t.MockGoFormat(data, write)
+
+		w := t.New(root, wd, data.Gosrc, data.Gopkg)
+
+		require.NoError(t, w.GoFormat(wollemi.Config{}, data.Paths))
+
+		var warnings []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["msg"] == "visibility wider than go internal package allows" {
+				warnings = append(warnings, map[string]interface{}{
+					"rule":       entry["rule"],
+					"visibility": entry["visibility"],
+					"allowed":    entry["allowed"],
+				})
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"rule":       "db",
+			"visibility": []string{"PUBLIC"},
+			"allowed":    "//app/...",
+		}}, warnings)
+	})
+
+	t.It("reports dependencies which are not visible to the package", func(t *T) {
+		data := &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"lib/db/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"//lib/..."}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/example/lib/db",
+						},
+					},
+				},
+			},
+		}
+
+		write := make(chan please.File, 10)
+
+		t.MockGoFormat(data, write)
+
+		w := t.New(root, wd, data.Gosrc, data.Gopkg)
+
+		require.NoError(t, w.GoFormat(wollemi.Config{}, data.Paths))
+
+		var errors []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["msg"] == "dependency not visible" {
+				errors = append(errors, map[string]interface{}{
+					"level":      entry["level"],
+					"dep":        entry["dep"],
+					"visibility": entry["visibility"],
+				})
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":      "error",
+			"dep":        "//lib/db:db",
+			"visibility": []string{"//lib/..."},
+		}}, errors)
+	})
+
+	t.It("suggests go module rules for unresolved imports found in the module cache", func(t *T) {
+		data := &GoFormatTestData{
+			Gosrc:      gosrc,
+			Gopkg:      gopkg,
+			Paths:      []string{"app/server"},
+			Parse:      t.WithThirdPartyGoModules(nil),
+			GoModCache: "/go/pkg/mod",
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/BurntSushi/toml",
+						},
+					},
+				},
+			},
+			Lstat: map[string]*FileInfo{
+				"/go/pkg/mod/github.com/!burnt!sushi/toml@v0.3.1": &FileInfo{
+					FileName:  "toml@v0.3.1",
+					FileMode:  os.ModeDir | 0755,
+					FileIsDir: true,
+				},
+				"/go/pkg/mod/github.com/!burnt!sushi/toml@v0.4.0": &FileInfo{
+					FileName:  "toml@v0.4.0",
+					FileMode:  os.ModeDir | 0755,
+					FileIsDir: true,
+				},
+			},
+			Stat: map[string]*FileInfo{
+				"/go/pkg/mod/github.com/!burnt!sushi/toml@v0.4.0": &FileInfo{
+					FileName:  "toml@v0.4.0",
+					FileMode:  os.ModeDir | 0755,
+					FileIsDir: true,
+				},
+			},
+		}
+
+		write := make(chan please.File, 10)
+
+		t.MockGoFormat(data, write)
+
+		w := t.New(root, wd, data.Gosrc, data.Gopkg)
+
+		require.NoError(t, w.GoFormat(wollemi.Config{}, data.Paths))
+
+		var suggested []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["msg"] == "suggested go module rule" {
+				delete(entry, "time")
+				suggested = append(suggested, entry)
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":     "warn",
+			"msg":       "suggested go module rule",
+			"go_import": "github.com/BurntSushi/toml",
+			"module":    "github.com/BurntSushi/toml",
+			"version":   "v0.4.0",
+			"path":      "/third_party/go/github.com/BurntSushi/toml",
+			"rule":      `go_module(name = "toml", module = "github.com/BurntSushi/toml", version = "v0.4.0", visibility = ["PUBLIC"])`,
+		}}, suggested)
+	})
+
+	t.It("skips unresolved imports found in the module cache without third party dirs", func(t *T) {
+		data := &GoFormatTestData{
+			Gosrc:      gosrc,
+			Gopkg:      gopkg,
+			Paths:      []string{"app/server"},
+			Parse:      t.WithThirdPartyGoModules(nil),
+			GoModCache: "/go/pkg/mod",
+			GoSum:      "github.com/BurntSushi/toml v0.4.0 h1:aaa=\n",
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles: []string{"server.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/BurntSushi/toml",
+						},
+					},
+				},
+			},
+		}
+
+		write := make(chan please.File, 10)
+
+		t.MockGoFormat(data, write)
+
+		w := t.New(root, wd, data.Gosrc, data.Gopkg)
+
+		config := wollemi.Config{
+			ThirdPartyDirs: []string{},
+			Gofmt: wollemi.Gofmt{
+				FixUnresolved: wollemiport.Bool(true),
+			},
+		}
+
+		require.NoError(t, w.GoFormat(config, data.Paths))
+
+		var msgs []interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] != "debug" {
+				msgs = append(msgs, entry["msg"])
+			}
+		}
+
+		assert.Equal(t, []interface{}{"could not resolve go import"}, msgs)
+	})
+
+	t.It("reports non test rules which depend on test only targets", func(t *T) {
+		data := &GoFormatTestData{
+			Gosrc: gosrc,
+			Gopkg: gopkg,
+			Paths: []string{"app/server"},
+			Parse: t.WithThirdPartyGo(map[string]*please.BuildFile{
+				"lib/fake/BUILD.plz": &please.BuildFile{
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "fake"),
+							please.NewAssignExpr("=", "srcs", please.NewGlob([]string{"*.go"}, "*_test.go")),
+							please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+							please.NewAssignExpr("=", "test_only", &please.Ident{Name: "True"}),
+						}),
+					},
+				},
+			}),
+			ImportDir: map[string]*golang.Package{
+				"app/server": &golang.Package{
+					GoFiles:     []string{"server.go"},
+					TestGoFiles: []string{"server_test.go"},
+					GoFileImports: map[string][]string{
+						"server.go": []string{
+							"github.com/example/lib/fake",
+						},
+						"server_test.go": []string{
+							"github.com/example/lib/fake",
+							"testing",
+						},
+					},
+				},
+			},
+		}
+
+		write := make(chan please.File, 10)
+
+		t.MockGoFormat(data, write)
+
+		w := t.New(root, wd, data.Gosrc, data.Gopkg)
+
+		require.NoError(t, w.GoFormat(wollemi.Config{}, data.Paths))
+
+		var errors []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["msg"] == "non test rule depends on test only target" {
+				errors = append(errors, map[string]interface{}{
+					"level": entry["level"],
+					"rule":  entry["rule"],
+					"dep":   entry["dep"],
+				})
+			}
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level": "error",
+			"rule":  "server",
+			"dep":   "//lib/fake",
+		}}, errors)
+	})
+
 	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
 		w := t.New(root, wd, gosrc, gopkg)
 
@@ -1789,8 +2981,21 @@ func (t *ServiceSuite) MockGoFormat(data *GoFormatTestData, write chan please.Fi
 			return &please.BuildFile{Path: path}, nil
 		})
 
+	t.golang.EXPECT().GOMODCACHE().AnyTimes().Return(data.GoModCache)
+
 	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
 		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			if path == "go.sum" {
+				if data.GoSum == "" {
+					return os.ErrNotExist
+				}
+
+				buf.Reset()
+				buf.WriteString(data.GoSum)
+
+				return nil
+			}
+
 			file, ok := data.Parse[path]
 			if !ok {
 				t.Errorf("unexpected call to filesystem read all: %s", path)
@@ -1865,15 +3070,25 @@ func (t *ServiceSuite) MockGoFormat(data *GoFormatTestData, write chan please.Fi
 			return info, nil
 		})
 
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
 	t.filesystem.EXPECT().Config(any).AnyTimes().
 		DoAndReturn(func(path string) wollemi.Config {
 			return data.Config[path]
 		})
 
+	t.filesystem.EXPECT().MkdirAll(any, any).AnyTimes().Return(nil)
+
 	t.please.EXPECT().NewRule(any, any).AnyTimes().DoAndReturn(please.NewRule)
 
 	t.please.EXPECT().Write(any).AnyTimes().
 		Do(func(have please.File) { write <- have })
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(data.PleaseConfig, nil)
+
+	if data.Graph != nil {
+		t.please.EXPECT().Graph().AnyTimes().Return(data.Graph, nil)
+	}
 }
 
 type GoFormatTestData struct {
@@ -1893,6 +3108,19 @@ type GoFormatTestData struct {
 	Readlink  map[string]string
 	Walk      []string
 	Graph     *please.Graph
+
+	GoSum      string
+	GoModCache string
+
+	PleaseConfig please.Config
+}
+
+// withBuildFileName returns a please config which defines the build file names.
+func withBuildFileName(names ...string) please.Config {
+	config := please.Config{}
+	config.Parse.BuildFileName = names
+
+	return config
 }
 
 // getFileImports gets combined list of imports from the provided files.
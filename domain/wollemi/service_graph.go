@@ -0,0 +1,123 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// graphAttrs are the rule attributes whose labels become the dependencies of
+// the rule in a graph built from parsed build files.
+var graphAttrs = []string{"deps", "exported_deps", "srcs", "data", "tools"}
+
+// parseGraph builds the build graph of the build files under the provided
+// paths without invoking please. Every label found in the graph attributes of
+// a rule becomes a dependency of the rule. Unlike the graph queried from
+// please it does not contain the hidden rules created by build definitions.
+func (this *Service) parseGraph(paths ...string) (*please.Graph, error) {
+	walk := make(chan *Directory, 1000)
+
+	if err := this.ReadDirs(walk, paths...); err != nil {
+		return nil, fmt.Errorf("could not walk: %v", err)
+	}
+
+	graph := &please.Graph{
+		Packages: make(map[string]*please.GraphPackage),
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	for dir := range walk {
+		if len(dir.BuildFiles) == 0 {
+			continue
+		}
+
+		file := this.parseBuildFile(buf, dir.Path)
+		if file == nil {
+			continue
+		}
+
+		pkg := &please.GraphPackage{
+			Targets: make(map[string]*please.GraphTarget),
+		}
+
+		file.GetRules(func(rule please.Rule) {
+			name := rule.Name()
+			if name == "" {
+				return
+			}
+
+			kind := rule.Kind()
+
+			target := &please.GraphTarget{
+				Binary:   strings.HasSuffix(kind, "_binary") || rule.AttrLiteral("binary") == "True",
+				Test:     strings.HasSuffix(kind, "_test"),
+				TestOnly: rule.AttrLiteral("test_only") == "True",
+			}
+
+			for _, attr := range graphAttrs {
+				for _, value := range exprStrings(rule.Attr(attr)) {
+					if label, ok := graphLabel(dir.Path, value); ok {
+						target.Deps = appendUniqString(target.Deps, label)
+					}
+				}
+			}
+
+			pkg.Targets[name] = target
+		})
+
+		graph.Packages[dir.Path] = pkg
+	}
+
+	return graph, nil
+}
+
+// exprStrings returns every string found in the expression, descending into
+// lists, dicts and concatenations but not into function calls such as glob.
+func exprStrings(expr please.Expr) []string {
+	switch expr := expr.(type) {
+	case *please.StringExpr:
+		return []string{expr.Value}
+	case *please.ListExpr:
+		var out []string
+		for _, entry := range expr.List {
+			out = append(out, exprStrings(entry)...)
+		}
+
+		return out
+	case *please.DictExpr:
+		var out []string
+		for _, entry := range expr.List {
+			out = append(out, exprStrings(entry)...)
+		}
+
+		return out
+	case *please.KeyValueExpr:
+		return exprStrings(expr.Value)
+	case *please.BinaryExpr:
+		return append(exprStrings(expr.X), exprStrings(expr.Y)...)
+	}
+
+	return nil
+}
+
+// graphLabel returns the absolute label of the rule referred to by the value
+// from the provided package, or false when the value is not a build label.
+func graphLabel(path, value string) (string, bool) {
+	switch {
+	case strings.HasPrefix(value, "//"):
+	case strings.HasPrefix(value, ":"):
+		value = "//" + path + value
+	default:
+		return "", false
+	}
+
+	target := please.Split(value)
+	if target.Name == "..." {
+		return "", false
+	}
+
+	return fmt.Sprintf("//%s:%s", target.Path, target.Name), true
+}
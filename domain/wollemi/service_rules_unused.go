@@ -2,58 +2,49 @@ package wollemi
 
 import (
 	"bytes"
-	"fmt"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/tcncloud/wollemi/ports/please"
+	"github.com/tcncloud/wollemi/ports/wollemi"
 )
 
-func (this *Service) RulesUnused(prune bool, kinds, paths, exclude []string) error {
+// RulesUnused reports the rules of the provided kinds under the provided paths
+// which no other rule depends on. When transitive is set rules whose dependents
+// are all unused themselves are reported as well, after the rules depending on
+// them. When roots are provided, as rule kinds or labels, every rule which is
+// not reachable from one of the root rules is reported instead. When prune is
+// set the unused rules are removed instead. When offline is set the build
+// graph is derived from the parsed build files rather than queried from please.
+func (this *Service) RulesUnused(prune, transitive, offline bool, roots, kinds, paths, exclude []string) error {
 	if err := this.validateAbsolutePaths(paths); err != nil {
 		return err
 	}
 
-	graph, err := this.please.Graph()
+	var graph *please.Graph
+	var err error
+
+	if offline {
+		graph, err = this.parseGraph("...")
+	} else {
+		graph, err = this.please.Graph()
+	}
+
 	if err != nil {
 		return err
 	}
 
 	paths = this.normalizePaths(paths)
 
+	config := this.filesystem.Config(".").Merge(this.config)
+
 	if len(kinds) == 0 {
-		kinds = []string{
-			"export_file",
-			"filegroup",
-			"genrule",
-			"go_get",
-			"go_get_with_sources",
-			"go_library",
-			"go_mock",
-			"go_module",
-			"grpc_library",
-			"pip_library",
-		}
+		kinds = config.Unused.GetKinds()
 	}
 
-	deps := make(map[string]struct{})
-	rules := make(map[string]struct{})
-	revdeps := make(map[string][]string)
-
-	for path, pkg := range graph.Packages {
-		for name, target := range pkg.Targets {
-			rule := path + ":" + name
-
-			rules[rule] = struct{}{}
-
-			for _, dep := range target.Deps {
-				dep = strings.TrimPrefix(dep, "//")
-				revdeps[dep] = append(revdeps[dep], rule)
-				deps[dep] = struct{}{}
-			}
-		}
-	}
+	rules, revdeps := graphReverseDeps(graph)
 
 	collect := make(chan *Directory, 1000)
 	parse := make(chan *Directory, 1000)
@@ -68,28 +59,7 @@ func (this *Service) RulesUnused(prune bool, kinds, paths, exclude []string) err
 			buf := bytes.NewBuffer(nil)
 
 			for dir := range parse {
-				dir.Build = (func() please.File {
-					log := this.log.WithField("path", dir.Path)
-
-					buildPath, err := this.FindBuildFile(dir.Path)
-					if err != nil {
-						log.WithError(err).Warn("could not find build file")
-						return nil
-					}
-
-					if err := this.filesystem.ReadAll(buf, buildPath); err != nil {
-						log.WithError(err).Warn("could not read build file")
-						return nil
-					}
-
-					file, err := this.please.Parse(buildPath, buf.Bytes())
-					if err != nil {
-						log.WithError(err).Warn("could not parse build file")
-						return nil
-					}
-
-					return file
-				}())
+				dir.Build = this.parseBuildFile(buf, dir.Path)
 
 				collect <- dir
 			}
@@ -108,21 +78,32 @@ func (this *Service) RulesUnused(prune bool, kinds, paths, exclude []string) err
 		}
 	}()
 
+	// Root kinds can only be matched once the build files of every package
+	// have been parsed.
+	var parseAll bool
+
+	for _, root := range roots {
+		if !strings.HasPrefix(root, "//") {
+			parseAll = true
+		}
+	}
+
 	parsed := make(map[string]struct{})
-	unused := make(map[string][]string)
+	names := make(map[string][]string)
 
 	for rule, _ := range rules {
-		if !inRunPath(rule, paths...) {
-			continue
-		}
+		candidate := inRunPath(rule, paths...) &&
+			(transitive || len(roots) > 0 || len(revdeps[rule]) == 0)
 
-		if len(revdeps[rule]) != 0 {
+		if !candidate && !parseAll {
 			continue
 		}
 
 		target := please.Split(rule)
 
-		unused[target.Path] = append(unused[target.Path], target.Name)
+		if candidate {
+			names[target.Path] = append(names[target.Path], target.Name)
+		}
 
 		if _, ok := parsed[target.Path]; ok {
 			continue
@@ -143,9 +124,17 @@ func (this *Service) RulesUnused(prune bool, kinds, paths, exclude []string) err
 		isIncludeKind[name] = struct{}{}
 	}
 
-Unused:
-	for path, names := range unused {
-		log := this.log.WithField("path", path)
+	var reachable map[string]bool
+
+	if len(roots) > 0 {
+		reachable = graphReachable(graph, rootRules(rules, dirs, roots))
+	}
+
+	ruleKinds := make(map[string]string)
+	dependents := make(map[string][]string)
+
+Candidates:
+	for path, names := range names {
 		dir, ok := dirs[path]
 		if !ok || dir == nil {
 			continue
@@ -158,13 +147,10 @@ Unused:
 
 		for _, prefix := range exclude {
 			if strings.HasPrefix(path, prefix) {
-				continue Unused
+				continue Candidates
 			}
 		}
 
-		var pruned int
-
-	UnusedNames:
 		for _, name := range names {
 			rule := dir.Build.GetRule(name)
 			if rule == nil {
@@ -177,36 +163,251 @@ Unused:
 				continue
 			}
 
-			switch kind {
-			case "grpc_library":
-				for _, x := range []string{"proto", "go", "java", "py", "ts"} {
-					if len(revdeps[fmt.Sprintf("%s:_%s#%s", path, name, x)]) != 0 {
-						continue UnusedNames
-					}
+			label := path + ":" + name
+
+			if reachable != nil {
+				if !isReachableRule(&config.Unused, reachable, path, name, kind) {
+					ruleKinds[label] = kind
+					dependents[label] = nil
 				}
-			case "pip_library":
-				if len(revdeps[path+":_"+name+"#wheel"]) != 0 {
-					continue
+
+				continue
+			}
+
+			deps := ruleDependents(&config.Unused, revdeps, path, name, kind)
+
+			if !transitive && len(deps) != 0 {
+				continue
+			}
+
+			ruleKinds[label] = kind
+			dependents[label] = deps
+		}
+	}
+
+	pruned := make(map[string]int)
+
+	for _, label := range unusedRules(dependents) {
+		target := please.Split(label)
+		dir := dirs[target.Path]
+
+		log := this.log.WithField("path", target.Path)
+
+		if prune {
+			if dir.Build.DelRule(target.Name) {
+				pruned[target.Path]++
+			}
+
+			continue
+		}
+
+		log = log.WithField("name", target.Name).
+			WithField("kind", ruleKinds[label])
+
+		if deps := dependents[label]; len(deps) > 0 {
+			var chain []string
+			for _, dep := range deps {
+				dep := please.Split(dep)
+				chain = appendUniqString(chain, "//"+dep.Path+":"+parentRuleName(dep.Name))
+			}
+
+			sort.Strings(chain)
+
+			log = log.WithField("dependents", chain)
+		}
+
+		log.Info("unused")
+	}
+
+	for path := range pruned {
+		if err := this.please.Write(dirs[path].Build); err != nil {
+			this.log.WithField("path", path).WithError(err).Warn("could not write")
+		}
+	}
+
+	return nil
+}
+
+// ruleDependents returns the rules which depend on the rule, or on one of the
+// sub targets of its kind. Hidden rules created by the rule itself are not
+// dependents.
+func ruleDependents(unused *wollemi.Unused, revdeps map[string][]string, path, name, kind string) []string {
+	var dependents []string
+
+	for _, label := range ruleLabels(unused, path, name, kind) {
+		for _, dep := range revdeps[label] {
+			target := please.Split(dep)
+
+			if target.Path != path || parentRuleName(target.Name) != name {
+				dependents = appendUniqString(dependents, dep)
+			}
+		}
+	}
+
+	return dependents
+}
+
+// ruleLabels returns the label of the rule followed by the labels of the sub
+// targets configured for its kind, which other rules may depend on instead.
+func ruleLabels(unused *wollemi.Unused, path, name, kind string) []string {
+	labels := []string{path + ":" + name}
+
+	for _, sub := range unused.GetSubTargets(kind, name) {
+		labels = append(labels, path+":"+sub)
+	}
+
+	return labels
+}
+
+// isReachableRule determines if the rule, or one of the sub targets of its
+// kind, is reachable.
+func isReachableRule(unused *wollemi.Unused, reachable map[string]bool, path, name, kind string) bool {
+	for _, label := range ruleLabels(unused, path, name, kind) {
+		if reachable[label] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rootRules returns the rules of the graph matched by the roots. A root which
+// starts with // is a label, or a label pattern ending in /..., whereas any
+// other root is a rule kind matched against the parsed build files.
+func rootRules(rules map[string]struct{}, dirs map[string]*Directory, roots []string) []string {
+	var matched []string
+
+Rules:
+	for rule := range rules {
+		target := please.Split(rule)
+
+		for _, root := range roots {
+			if strings.HasPrefix(root, "//") {
+				label := please.Split(root)
+
+				if label.Name == "..." {
+					if inRunPath(rule, strings.TrimPrefix(root, "//")) {
+						matched = append(matched, rule)
+						continue Rules
+					}
+				} else if label.Path == target.Path && label.Name == target.Name {
+					matched = append(matched, rule)
+					continue Rules
 				}
+
+				continue
+			}
+
+			dir, ok := dirs[target.Path]
+			if !ok || dir == nil || dir.Build == nil {
+				continue
 			}
 
-			if prune {
-				if dir.Build.DelRule(name) {
-					pruned++
+			if r := dir.Build.GetRule(target.Name); r != nil && r.Kind() == root {
+				matched = append(matched, rule)
+				continue Rules
+			}
+		}
+	}
+
+	return matched
+}
+
+// graphReachable returns every rule of the graph reachable from the provided
+// rules by following their dependencies, including the rules themselves.
+func graphReachable(graph *please.Graph, from []string) map[string]bool {
+	reachable := make(map[string]bool)
+
+	for len(from) > 0 {
+		rule := from[len(from)-1]
+		from = from[:len(from)-1]
+
+		if reachable[rule] {
+			continue
+		}
+
+		reachable[rule] = true
+
+		target := please.Split(rule)
+
+		pkg, ok := graph.Packages[target.Path]
+		if !ok {
+			continue
+		}
+
+		if t, ok := pkg.Targets[target.Name]; ok {
+			for _, dep := range t.Deps {
+				dep := please.Split(dep)
+				from = append(from, dep.Path+":"+dep.Name)
+			}
+		}
+	}
+
+	return reachable
+}
+
+// unusedRules returns the rules whose dependents are all unused rules
+// themselves, given the dependents of every candidate rule. Rules without any
+// dependents come first and every other rule comes after its dependents, which
+// is the order in which they can be pruned. Dependents are attributed to the
+// rule which created them so hidden rules do not keep their dependencies used.
+func unusedRules(dependents map[string][]string) []string {
+	unused := make(map[string]bool, len(dependents))
+
+	var order []string
+
+	for {
+		var round []string
+
+	Candidates:
+		for label, deps := range dependents {
+			if unused[label] {
+				continue
+			}
+
+			for _, dep := range deps {
+				target := please.Split(dep)
+
+				if !unused[target.Path+":"+parentRuleName(target.Name)] {
+					continue Candidates
 				}
-			} else {
-				log.WithField("name", name).
-					WithField("kind", kind).
-					Info("unused")
 			}
+
+			round = append(round, label)
+		}
+
+		if len(round) == 0 {
+			return order
 		}
 
-		if pruned > 0 {
-			if err := this.please.Write(dir.Build); err != nil {
-				log.WithError(err).Warn("could not write")
+		sort.Strings(round)
+
+		for _, label := range round {
+			unused[label] = true
+		}
+
+		order = append(order, round...)
+	}
+}
+
+// graphReverseDeps returns every rule of the graph along with the rules which
+// depend on each rule. Rules are given as path:name without a leading //.
+func graphReverseDeps(graph *please.Graph) (map[string]struct{}, map[string][]string) {
+	rules := make(map[string]struct{})
+	revdeps := make(map[string][]string)
+
+	for path, pkg := range graph.Packages {
+		for name, target := range pkg.Targets {
+			rule := path + ":" + name
+
+			rules[rule] = struct{}{}
+
+			for _, dep := range target.Deps {
+				dep = strings.TrimPrefix(dep, "//")
+				revdeps[dep] = append(revdeps[dep], rule)
 			}
 		}
 	}
 
-	return nil
+	return rules, revdeps
 }
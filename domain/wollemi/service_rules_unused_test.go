@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/tcncloud/wollemi/domain/wollemi"
 	"github.com/tcncloud/wollemi/testdata/expect"
 	"github.com/tcncloud/wollemi/testdata/please"
 )
@@ -26,12 +28,15 @@ func (t *ServiceSuite) TestService_RulesUnused() {
 
 		var (
 			prune        bool
+			transitive   bool
+			offline      bool
+			roots        []string
 			kinds        []string
 			paths        []string
 			excludePaths []string
 		)
 
-		wollemi.RulesUnused(prune, kinds, paths, excludePaths)
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
 
 		want := []map[string]interface{}{
 			map[string]interface{}{
@@ -106,28 +111,446 @@ func (t *ServiceSuite) TestService_RulesUnused() {
 
 		var (
 			prune        bool = true
+			transitive   bool
+			offline      bool
+			roots        []string
 			kinds        []string
 			paths        []string
 			excludePaths []string
 		)
 
-		wollemi.RulesUnused(prune, kinds, paths, excludePaths)
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
+	})
+
+	t.It("can list transitively unused build rules", func(t *T) {
+		t.MockRulesUnused()
+
+		wollemi := t.New(root, wd, gosrc, gopkg)
+
+		var (
+			prune        bool
+			transitive   bool = true
+			offline      bool
+			roots        []string
+			kinds        []string
+			paths        []string
+			excludePaths []string
+		)
+
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
+
+		want := []map[string]interface{}{
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "filegroup",
+				"msg":   "unused",
+				"name":  "files",
+				"path":  "app",
+			},
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "go_get",
+				"msg":   "unused",
+				"name":  "viper",
+				"path":  "third_party/go/github.com/spf13",
+			},
+			map[string]interface{}{
+				"level":      "info",
+				"kind":       "go_get",
+				"msg":        "unused",
+				"name":       "fsnotify",
+				"path":       "third_party/go/github.com/fsnotify",
+				"dependents": []string{"//third_party/go/github.com/spf13:viper"},
+			},
+			map[string]interface{}{
+				"level":      "info",
+				"kind":       "go_get",
+				"msg":        "unused",
+				"name":       "sys",
+				"path":       "third_party/go/golang.org/x",
+				"dependents": []string{"//third_party/go/github.com/fsnotify:fsnotify"},
+			},
+		}
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, want, have)
+	})
+
+	t.It("can prune transitively unused build rules", func(t *T) {
+		t.MockRulesUnused()
+
+		var written []string
+
+		t.please.EXPECT().Write(any).Times(4).Do(func(have please.File) {
+			written = append(written, have.GetPath())
+
+			for _, name := range []string{"files", "viper", "fsnotify", "sys"} {
+				if have.GetRule(name) != nil {
+					t.Errorf("unexpected rule after prune: %s", name)
+				}
+			}
+		})
+
+		wollemi := t.New(root, wd, gosrc, gopkg)
+
+		var (
+			prune        bool = true
+			transitive   bool = true
+			offline      bool
+			roots        []string
+			kinds        []string
+			paths        []string
+			excludePaths []string
+		)
+
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
+
+		assert.ElementsMatch(t, []string{
+			"app/BUILD.plz",
+			"third_party/go/github.com/spf13/BUILD.plz",
+			"third_party/go/github.com/fsnotify/BUILD.plz",
+			"third_party/go/golang.org/x/BUILD.plz",
+		}, written)
+	})
+
+	t.It("can list build rules not reachable from root kinds", func(t *T) {
+		t.MockRulesUnused()
+
+		wollemi := t.New(root, wd, gosrc, gopkg)
+
+		var (
+			prune        bool
+			transitive   bool
+			offline      bool
+			roots        = []string{"app"}
+			kinds        []string
+			paths        []string
+			excludePaths []string
+		)
+
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
+
+		want := []map[string]interface{}{
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "filegroup",
+				"msg":   "unused",
+				"name":  "files",
+				"path":  "app",
+			},
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "go_get",
+				"msg":   "unused",
+				"name":  "fsnotify",
+				"path":  "third_party/go/github.com/fsnotify",
+			},
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "go_get",
+				"msg":   "unused",
+				"name":  "viper",
+				"path":  "third_party/go/github.com/spf13",
+			},
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "go_get",
+				"msg":   "unused",
+				"name":  "sys",
+				"path":  "third_party/go/golang.org/x",
+			},
+		}
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, want, have)
+	})
+
+	t.It("can list build rules not reachable from root labels", func(t *T) {
+		t.MockRulesUnused()
+
+		wollemi := t.New(root, wd, gosrc, gopkg)
+
+		var (
+			prune        bool
+			transitive   bool
+			offline      bool
+			roots        = []string{"//third_party/go/github.com/spf13/..."}
+			kinds        []string
+			paths        []string
+			excludePaths []string
+		)
+
+		wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
+
+		want := []map[string]interface{}{
+			map[string]interface{}{
+				"level": "info",
+				"kind":  "filegroup",
+				"msg":   "unused",
+				"name":  "files",
+				"path":  "app",
+			},
+		}
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, want, have)
+	})
+
+	t.It("uses sub target conventions from config", func(t *T) {
+		graph := &please.Graph{
+			Packages: map[string]*please.GraphPackage{
+				"app": &please.GraphPackage{
+					Targets: map[string]*please.GraphTarget{
+						"app": &please.GraphTarget{
+							Deps: []string{"//lib/api:_api#lib"},
+						},
+					},
+				},
+				"lib/api": &please.GraphPackage{
+					Targets: map[string]*please.GraphTarget{
+						"api":      &please.GraphTarget{},
+						"_api#lib": &please.GraphTarget{},
+					},
+				},
+				"lib/auth": &please.GraphPackage{
+					Targets: map[string]*please.GraphTarget{
+						"auth":      &please.GraphTarget{},
+						"_auth#lib": &please.GraphTarget{},
+					},
+				},
+			},
+		}
+
+		files := map[string]*please.BuildFile{
+			"lib/api/BUILD.plz": &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_service", []please.Expr{
+						please.NewAssignExpr("=", "name", "api"),
+					}),
+				},
+			},
+			"lib/auth/BUILD.plz": &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_service", []please.Expr{
+						please.NewAssignExpr("=", "name", "auth"),
+					}),
+				},
+			},
+		}
+
+		t.please.EXPECT().Graph().Return(graph, nil)
+
+		t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+			DoAndReturn(func(path string) ([]os.FileInfo, error) {
+				if _, ok := files[filepath.Join(path, "BUILD.plz")]; !ok {
+					t.Errorf("unexpected call to filesystem read dir: %s", path)
+					return nil, os.ErrNotExist
+				}
+
+				return []os.FileInfo{
+					&FileInfo{
+						FileName: "BUILD.plz",
+						FileMode: os.FileMode(420),
+					},
+				}, nil
+			})
+
+		t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+			DoAndReturn(func(buf *bytes.Buffer, path string) error {
+				buf.Reset()
+				buf.WriteString(path)
+
+				return nil
+			})
+
+		t.please.EXPECT().Parse(any, any).AnyTimes().
+			DoAndReturn(func(path string, data []byte) (please.File, error) {
+				file := files[path]
+				file.Path = path
+
+				return file, nil
+			})
+
+		t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+		t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+		t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{
+			Unused: wollemi.Unused{
+				Kinds: []string{"go_service"},
+				SubTargets: map[string][]string{
+					"go_service": []string{"_{name}#lib"},
+				},
+			},
+		})
+
+		t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+
+		w := t.New(root, wd, gosrc, gopkg)
+
+		require.NoError(t, w.RulesUnused(false, false, false, nil, nil, []string{"lib/..."}, nil))
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level": "info",
+			"kind":  "go_service",
+			"msg":   "unused",
+			"name":  "auth",
+			"path":  "lib/auth",
+		}}, have)
+	})
+
+	t.It("can list unused build rules using a graph derived from build files", func(t *T) {
+		dirs := map[string][]os.FileInfo{
+			".": []os.FileInfo{
+				&FileInfo{FileName: "app", FileMode: os.ModeDir | 0755, FileIsDir: true},
+				&FileInfo{FileName: "lib", FileMode: os.ModeDir | 0755, FileIsDir: true},
+			},
+			"app": []os.FileInfo{
+				&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+			},
+			"lib": []os.FileInfo{
+				&FileInfo{FileName: "db", FileMode: os.ModeDir | 0755, FileIsDir: true},
+			},
+			"lib/db": []os.FileInfo{
+				&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+			},
+		}
+
+		files := map[string]*please.BuildFile{
+			"app/BUILD.plz": &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_binary", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "deps", []string{"//lib/db"}),
+					}),
+					please.NewCallExpr("filegroup", []please.Expr{
+						please.NewAssignExpr("=", "name", "files"),
+						please.NewAssignExpr("=", "srcs", []string{"config.yaml"}),
+					}),
+				},
+			},
+			"lib/db/BUILD.plz": &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "db"),
+						please.NewAssignExpr("=", "srcs", []string{"db.go", ":schema"}),
+					}),
+					please.NewCallExpr("genrule", []please.Expr{
+						please.NewAssignExpr("=", "name", "schema"),
+						please.NewAssignExpr("=", "tools", []string{":migrate"}),
+					}),
+					please.NewCallExpr("filegroup", []please.Expr{
+						please.NewAssignExpr("=", "name", "migrate"),
+					}),
+					please.NewCallExpr("genrule", []please.Expr{
+						please.NewAssignExpr("=", "name", "fixtures"),
+					}),
+				},
+			},
+		}
+
+		t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+			DoAndReturn(func(path string) ([]os.FileInfo, error) {
+				infos, ok := dirs[path]
+				if !ok {
+					t.Errorf("unexpected call to filesystem read dir: %s", path)
+					return nil, os.ErrNotExist
+				}
+
+				return infos, nil
+			})
+
+		t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+			DoAndReturn(func(buf *bytes.Buffer, path string) error {
+				if _, ok := files[path]; !ok {
+					t.Errorf("unexpected call to filesystem read all: %s", path)
+				}
+
+				buf.Reset()
+				buf.WriteString(path)
+
+				return nil
+			})
+
+		t.please.EXPECT().Parse(any, any).AnyTimes().
+			DoAndReturn(func(path string, data []byte) (please.File, error) {
+				file := files[path]
+				file.Path = path
+
+				return file, nil
+			})
+
+		t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+		t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+		t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+		t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+
+		w := t.New(root, wd, gosrc, gopkg)
+
+		require.NoError(t, w.RulesUnused(false, false, true, nil, nil, nil, nil))
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.ElementsMatch(t, []map[string]interface{}{{
+			"level": "info",
+			"kind":  "filegroup",
+			"msg":   "unused",
+			"name":  "files",
+			"path":  "app",
+		}, {
+			"level": "info",
+			"kind":  "genrule",
+			"msg":   "unused",
+			"name":  "fixtures",
+			"path":  "lib/db",
+		}}, have)
 	})
 
 	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
 		wollemi := t.New(root, wd, gosrc, gopkg)
 
 		var (
-			prune bool = true
-			kinds []string
-			paths = []string{
+			prune      bool = true
+			transitive bool
+			offline    bool
+			roots      []string
+			kinds      []string
+			paths      = []string{
 				filepath.Join(root, "subdir"),
 				"/outside/of/root",
 			}
 			excludePaths []string
 		)
 
-		err := wollemi.RulesUnused(prune, kinds, paths, excludePaths)
+		err := wollemi.RulesUnused(prune, transitive, offline, roots, kinds, paths, excludePaths)
 
 		assert.Error(t, err)
 	})
@@ -153,10 +576,33 @@ func (t *ServiceSuite) MockRulesUnused() {
 							"//third_party/go/github.com/spf13:pflag",
 						},
 					},
-					"viper": &please.GraphTarget{},
+					"viper": &please.GraphTarget{
+						Deps: []string{
+							"//third_party/go/github.com/fsnotify:fsnotify",
+						},
+					},
 					"pflag": &please.GraphTarget{},
 				},
 			},
+			"third_party/go/github.com/fsnotify": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"fsnotify": &please.GraphTarget{
+						Deps: []string{
+							"//third_party/go/github.com/fsnotify:_fsnotify#download",
+						},
+					},
+					"_fsnotify#download": &please.GraphTarget{
+						Deps: []string{
+							"//third_party/go/golang.org/x:sys",
+						},
+					},
+				},
+			},
+			"third_party/go/golang.org/x": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"sys": &please.GraphTarget{},
+				},
+			},
 		},
 	}
 
@@ -180,7 +626,9 @@ func (t *ServiceSuite) MockRulesUnused() {
 						FileMode: os.FileMode(420),
 					},
 				}
-			case "third_party/go/github.com/spf13":
+			case "third_party/go/github.com/spf13",
+				"third_party/go/github.com/fsnotify",
+				"third_party/go/golang.org/x":
 				infos = []os.FileInfo{
 					&FileInfo{
 						FileName: "BUILD.plz",
@@ -200,6 +648,8 @@ func (t *ServiceSuite) MockRulesUnused() {
 			switch path {
 			case "app/BUILD.plz":
 			case "third_party/go/github.com/spf13/BUILD.plz":
+			case "third_party/go/github.com/fsnotify/BUILD.plz":
+			case "third_party/go/golang.org/x/BUILD.plz":
 			default:
 				t.Errorf("unexpected call to filesystem read all: %s", path)
 			}
@@ -251,6 +701,34 @@ func (t *ServiceSuite) MockRulesUnused() {
 							please.NewAssignExpr("=", "name", "viper"),
 							please.NewAssignExpr("=", "get", "github.com/spf13/pflag"),
 							please.NewAssignExpr("=", "revision", "v1.6.3"),
+							please.NewAssignExpr("=", "deps", []string{
+								"//third_party/go/github.com/fsnotify",
+							}),
+						}),
+					},
+				}
+			case "third_party/go/github.com/fsnotify/BUILD.plz":
+				file = &please.BuildFile{
+					Path: path,
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_get", []please.Expr{
+							please.NewAssignExpr("=", "name", "fsnotify"),
+							please.NewAssignExpr("=", "get", "github.com/fsnotify/fsnotify"),
+							please.NewAssignExpr("=", "revision", "v1.4.9"),
+							please.NewAssignExpr("=", "deps", []string{
+								"//third_party/go/golang.org/x:sys",
+							}),
+						}),
+					},
+				}
+			case "third_party/go/golang.org/x/BUILD.plz":
+				file = &please.BuildFile{
+					Path: path,
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_get", []please.Expr{
+							please.NewAssignExpr("=", "name", "sys"),
+							please.NewAssignExpr("=", "get", "golang.org/x/sys/..."),
+							please.NewAssignExpr("=", "revision", "v0.0.0-20200323222414-85ca7c5b95cd"),
 						}),
 					},
 				}
@@ -260,4 +738,12 @@ func (t *ServiceSuite) MockRulesUnused() {
 
 			return file, nil
 		})
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
 }
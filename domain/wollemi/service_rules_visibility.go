@@ -0,0 +1,176 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// RulesVisibility reports the minimal visibility of every rule under the
+// provided paths which covers the packages currently depending on it. When
+// tighten is set explicit visibility attributes wider than the minimal
+// visibility are rewritten. Visibility is never widened.
+func (this *Service) RulesVisibility(tighten bool, kinds, paths []string) error {
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return err
+	}
+
+	graph, err := this.please.Graph()
+	if err != nil {
+		return err
+	}
+
+	paths = this.normalizePaths(paths)
+
+	if len(kinds) == 0 {
+		kinds = []string{"go_library"}
+	}
+
+	_, revdeps := graphReverseDeps(graph)
+
+	var pkgs []string
+
+	for path := range graph.Packages {
+		if inRunPath(path+":", paths...) {
+			pkgs = append(pkgs, path)
+		}
+	}
+
+	sort.Strings(pkgs)
+
+	buf := bytes.NewBuffer(nil)
+
+	for _, path := range pkgs {
+		log := this.log.WithField("path", path)
+
+		file := this.parseBuildFile(buf, path)
+		if file == nil {
+			continue
+		}
+
+		var tightened int
+
+		file.GetRules(func(rule please.Rule) {
+			if !inStrings(kinds, rule.Kind()) || rule.Attr("visibility") == nil {
+				return
+			}
+
+			log := log.WithField("rule", rule.Name())
+
+			var dependents []string
+
+			for _, dep := range revdeps[path+":"+rule.Name()] {
+				if pkg := please.Split(dep).Path; pkg != path {
+					dependents = appendUniqString(dependents, pkg)
+				}
+			}
+
+			if len(dependents) == 0 {
+				log.Debug("no dependents outside of package")
+				return
+			}
+
+			visibility := rule.AttrStrings("visibility")
+			minimal := minimalVisibility(dependents)
+
+			if len(visibility) == len(minimal) && inStrings(visibility, minimal...) {
+				return
+			}
+
+			log = log.WithField("visibility", visibility).
+				WithField("minimal", minimal)
+
+			if !visibilityCovers(visibility, minimal) {
+				log.WithField("reason", "visibility does not grant minimal visibility").
+					Warn("could not tighten visibility")
+				return
+			}
+
+			if !tighten {
+				log.Info("visibility can be tightened")
+				return
+			}
+
+			rule.SetAttr("visibility", please.Strings(minimal...))
+			tightened++
+
+			log.Info("tightened visibility")
+		})
+
+		if tightened > 0 {
+			if err := this.please.Write(file); err != nil {
+				log.WithError(err).Warn("could not write")
+			}
+		}
+	}
+
+	return nil
+}
+
+// minimalVisibility returns the fewest //dir/... visibilities which cover the
+// dependent packages. Dependents are grouped by their top level directory and
+// each group is covered by the longest directory common to the group.
+func minimalVisibility(dependents []string) []string {
+	common := make(map[string]string)
+
+	for _, pkg := range dependents {
+		if pkg == "" || pkg == "." {
+			return []string{"//..."}
+		}
+
+		top := strings.SplitN(pkg, "/", 2)[0]
+
+		if dir, ok := common[top]; ok {
+			common[top] = commonDir(dir, pkg)
+		} else {
+			common[top] = pkg
+		}
+	}
+
+	visibility := make([]string, 0, len(common))
+
+	for _, dir := range common {
+		visibility = append(visibility, fmt.Sprintf("//%s/...", dir))
+	}
+
+	sort.Strings(visibility)
+
+	return visibility
+}
+
+// commonDir returns the longest directory which contains both directories.
+func commonDir(a, b string) string {
+	for a != b {
+		if len(a) > len(b) {
+			a = filepath.Dir(a)
+		} else {
+			b = filepath.Dir(b)
+		}
+	}
+
+	return a
+}
+
+// visibilityCovers determines if every wanted visibility is granted by the
+// visibility.
+func visibilityCovers(visibility, want []string) bool {
+Want:
+	for _, x := range want {
+		for _, y := range visibility {
+			switch {
+			case y == "PUBLIC", y == x:
+				continue Want
+			case strings.HasSuffix(y, "/...") && visibilityWithin(x, y):
+				continue Want
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
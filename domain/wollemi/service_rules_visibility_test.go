@@ -0,0 +1,249 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tcncloud/wollemi/testdata/expect"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_RulesVisibility(t *testing.T) {
+	NewServiceSuite(t).TestService_RulesVisibility()
+}
+
+func (t *ServiceSuite) TestService_RulesVisibility() {
+	type T = ServiceSuite
+
+	t.It("lists rules whose visibility can be tightened", func(t *T) {
+		t.MockRulesVisibility()
+
+		err := t.New(root, wd, gosrc, gopkg).RulesVisibility(false, nil, nil)
+		assert.NoError(t, err)
+
+		var lines []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			delete(entry, "time")
+			lines = append(lines, entry)
+		}
+
+		assert.ElementsMatch(t, []map[string]interface{}{{
+			"level":      "info",
+			"msg":        "visibility can be tightened",
+			"path":       "lib/db",
+			"rule":       "db",
+			"visibility": []string{"PUBLIC"},
+			"minimal":    []string{"//app/...", "//tools/migrate/..."},
+		}, {
+			"level":      "info",
+			"msg":        "visibility can be tightened",
+			"path":       "lib/log",
+			"rule":       "log",
+			"visibility": []string{"//app/..."},
+			"minimal":    []string{"//app/server/..."},
+		}, {
+			"level":      "warn",
+			"msg":        "could not tighten visibility",
+			"path":       "lib/cfg",
+			"rule":       "cfg",
+			"reason":     "visibility does not grant minimal visibility",
+			"visibility": []string{"//lib/..."},
+			"minimal":    []string{"//app/server/..."},
+		}}, lines)
+	})
+
+	t.It("tightens visibility of rules", func(t *T) {
+		t.MockRulesVisibility()
+
+		t.please.EXPECT().Write(any).Times(2).Do(func(have please.File) {
+			path := have.GetPath()
+
+			var want *please.BuildFile
+
+			switch path {
+			case "lib/db/BUILD.plz":
+				want = &please.BuildFile{
+					Path: path,
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "db"),
+							please.NewAssignExpr("=", "visibility", []string{
+								"//app/...",
+								"//tools/migrate/...",
+							}),
+						}),
+						please.NewCallExpr("go_test", []please.Expr{
+							please.NewAssignExpr("=", "name", "test"),
+							please.NewAssignExpr("=", "deps", []string{":db"}),
+						}),
+					},
+				}
+			case "lib/log/BUILD.plz":
+				want = &please.BuildFile{
+					Path: path,
+					Stmt: []please.Expr{
+						please.NewCallExpr("go_library", []please.Expr{
+							please.NewAssignExpr("=", "name", "log"),
+							please.NewAssignExpr("=", "visibility", []string{"//app/server/..."}),
+						}),
+					},
+				}
+			default:
+				t.Errorf("unexpected call to please write: %s", path)
+			}
+
+			expect.Equal(t, want, have)
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).RulesVisibility(true, nil, []string{"lib/..."})
+		assert.NoError(t, err)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).RulesVisibility(false, nil, []string{"/outside/of/root"})
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockRulesVisibility() {
+	graph := &please.Graph{
+		Packages: map[string]*please.GraphPackage{
+			"app/server": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"server": &please.GraphTarget{
+						Deps: []string{"//lib/db:db", "//lib/log:log", "//lib/cfg:cfg"},
+					},
+				},
+			},
+			"app/client": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"client": &please.GraphTarget{
+						Deps: []string{"//lib/db:db"},
+					},
+				},
+			},
+			"tools/migrate": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"migrate": &please.GraphTarget{
+						Deps: []string{"//lib/db:db"},
+					},
+				},
+			},
+			"lib/db": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"db": &please.GraphTarget{},
+					"test": &please.GraphTarget{
+						Deps: []string{"//lib/db:db"},
+					},
+				},
+			},
+			"lib/log": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"log": &please.GraphTarget{},
+				},
+			},
+			"lib/cfg": &please.GraphPackage{
+				Targets: map[string]*please.GraphTarget{
+					"cfg": &please.GraphTarget{},
+				},
+			},
+		},
+	}
+
+	files := map[string]*please.BuildFile{
+		"app/server/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_binary", []please.Expr{
+					please.NewAssignExpr("=", "name", "server"),
+				}),
+			},
+		},
+		"app/client/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_binary", []please.Expr{
+					please.NewAssignExpr("=", "name", "client"),
+				}),
+			},
+		},
+		"tools/migrate/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_binary", []please.Expr{
+					please.NewAssignExpr("=", "name", "migrate"),
+				}),
+			},
+		},
+		"lib/db/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_library", []please.Expr{
+					please.NewAssignExpr("=", "name", "db"),
+					please.NewAssignExpr("=", "visibility", []string{"PUBLIC"}),
+				}),
+				please.NewCallExpr("go_test", []please.Expr{
+					please.NewAssignExpr("=", "name", "test"),
+					please.NewAssignExpr("=", "deps", []string{":db"}),
+				}),
+			},
+		},
+		"lib/log/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_library", []please.Expr{
+					please.NewAssignExpr("=", "name", "log"),
+					please.NewAssignExpr("=", "visibility", []string{"//app/..."}),
+				}),
+			},
+		},
+		"lib/cfg/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_library", []please.Expr{
+					please.NewAssignExpr("=", "name", "cfg"),
+					please.NewAssignExpr("=", "visibility", []string{"//lib/..."}),
+				}),
+			},
+		},
+	}
+
+	t.please.EXPECT().Graph().Return(graph, nil)
+
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) ([]os.FileInfo, error) {
+			if _, ok := files[filepath.Join(path, "BUILD.plz")]; !ok {
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			return []os.FileInfo{
+				&FileInfo{
+					FileName: "BUILD.plz",
+					FileMode: os.FileMode(420),
+				},
+			}, nil
+		})
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			if _, ok := files[path]; !ok {
+				t.Errorf("unexpected call to filesystem read all: %s", path)
+			}
+
+			buf.Reset()
+			buf.WriteString(path)
+
+			return nil
+		})
+
+	t.please.EXPECT().Parse(any, any).AnyTimes().
+		DoAndReturn(func(path string, data []byte) (please.File, error) {
+			assert.Equal(t, string(data), path)
+
+			file := files[path]
+			file.Path = path
+
+			return file, nil
+		})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
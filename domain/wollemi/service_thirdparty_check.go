@@ -0,0 +1,306 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// ThirdpartyCheck reports the third party go rules under the provided paths,
+// or under the third party dirs when no paths are provided, which provide the
+// same go module. Rules of the same module at different semantic versions are
+// reported as conflicts and otherwise, including when a version is a commit
+// hash, as duplicates. Rules whose module is also installed by a broader rule,
+// either by the wildcard of a go_get rule or by the install list of a go_module
+// rule naming the package, are reported as well. Each report suggests a
+// canonical target to depend on instead.
+func (this *Service) ThirdpartyCheck(paths []string) error {
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		for _, dir := range this.filesystem.Config(".").Merge(this.config).GetThirdPartyDirs() {
+			paths = append(paths, filepath.Join(dir, "..."))
+		}
+	} else {
+		paths = this.normalizePaths(paths)
+	}
+
+	rules, err := this.parseThirdPartyRules(paths)
+	if err != nil {
+		return err
+	}
+
+	modules := make(map[string][]*thirdPartyRule)
+
+	for _, rule := range rules {
+		modules[rule.Module] = append(modules[rule.Module], rule)
+	}
+
+	names := make([]string, 0, len(modules))
+	for module := range modules {
+		names = append(names, module)
+	}
+
+	sort.Strings(names)
+
+	for _, module := range names {
+		same := modules[module]
+		if len(same) < 2 {
+			continue
+		}
+
+		var labels, versions []string
+
+		for _, rule := range same {
+			labels = append(labels, rule.Label)
+
+			if semver.IsValid(rule.Version) {
+				versions = appendUniqString(versions, rule.Version)
+			}
+		}
+
+		log := this.log.WithField("module", module).
+			WithField("rules", labels).
+			WithField("canonical", canonicalThirdPartyRule(same).Label)
+
+		if len(versions) > 1 {
+			sort.Strings(versions)
+
+			log.WithField("versions", versions).Error("conflicting third party module versions")
+		} else {
+			log.Warn("duplicate third party module rules")
+		}
+	}
+
+	for _, rule := range rules {
+		for _, other := range rules {
+			if other.Module == rule.Module {
+				continue
+			}
+
+			pattern, ok := other.covers(rule.Module)
+			if !ok {
+				continue
+			}
+
+			this.log.WithField("rule", rule.Label).
+				WithField("module", rule.Module).
+				WithField("covered_by", other.Label).
+				WithField("install", pattern).
+				WithField("canonical", other.Label).
+				Warn("third party module covered by install entry")
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// thirdPartyRule is a third party go rule and the go module it provides.
+type thirdPartyRule struct {
+	Label   string
+	Kind    string
+	Module  string
+	Version string
+	Path    string
+	Rule    please.Rule
+	File    please.File
+
+	// Packages are the import paths installed by the rule, which install every
+	// package under them when ending in /...
+	Packages []string
+}
+
+// covers returns the package of the rule which installs the go package and
+// false when there is none. Wildcard packages of go_module rules stop at the
+// boundaries of nested modules, which can not be known without the sources of
+// the module, so only the packages they name explicitly are considered.
+func (this *thirdPartyRule) covers(pkg string) (string, bool) {
+	for _, pattern := range this.Packages {
+		if this.Kind == "go_module" {
+			if pattern == pkg {
+				return pattern, true
+			}
+
+			continue
+		}
+
+		if !strings.HasSuffix(pattern, "/...") {
+			continue
+		}
+
+		base := strings.TrimSuffix(pattern, "/...")
+
+		if pkg == base || strings.HasPrefix(pkg, base+"/") {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// parseThirdPartyRules returns the third party go rules of the build files
+// under the provided paths sorted by label.
+func (this *Service) parseThirdPartyRules(paths []string) ([]*thirdPartyRule, error) {
+	walk := make(chan *Directory, 1000)
+
+	if err := this.ReadDirs(walk, paths...); err != nil {
+		return nil, fmt.Errorf("could not walk: %v", err)
+	}
+
+	var rules []*thirdPartyRule
+
+	buf := bytes.NewBuffer(nil)
+
+	for dir := range walk {
+		if len(dir.BuildFiles) == 0 {
+			continue
+		}
+
+		file := this.parseBuildFile(buf, dir.Path)
+		if file == nil {
+			continue
+		}
+
+		file.GetRules(func(rule please.Rule) {
+			if x, ok := newThirdPartyRule(dir.Path, rule); ok {
+				x.File = file
+				rules = append(rules, x)
+			}
+		})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Label < rules[j].Label
+	})
+
+	return rules, nil
+}
+
+// newThirdPartyRule returns the third party go rule of the go_module, go_get
+// and go_get_with_sources rules, matched as when resolving go imports, and
+// false for any other rule.
+func newThirdPartyRule(path string, rule please.Rule) (*thirdPartyRule, bool) {
+	out := &thirdPartyRule{
+		Label: fmt.Sprintf("//%s:%s", path, rule.Name()),
+		Kind:  rule.Kind(),
+		Path:  path,
+		Rule:  rule,
+	}
+
+	switch out.Kind {
+	case "go_module":
+		out.Module = rule.AttrString("module")
+		out.Version = rule.AttrString("version")
+
+		install := rule.AttrStrings("install")
+		if len(install) == 0 {
+			install = []string{"."} // Empty install list installs the root package
+		}
+
+		for _, entry := range install {
+			pkg := out.Module
+			if install := filepath.Clean(strings.TrimSuffix(entry, "...")); install != "." {
+				pkg = filepath.Join(pkg, install)
+			}
+
+			if strings.HasSuffix(entry, "...") {
+				pkg += "/..."
+			}
+
+			out.Packages = append(out.Packages, pkg)
+		}
+	case "go_get", "go_get_with_sources":
+		if rule.AttrLiteral("binary") == "True" {
+			return nil, false
+		}
+
+		out.Module = goGetPath(rule)
+		out.Version = rule.AttrString("revision")
+
+		if get := rule.AttrString("get"); get != "" {
+			out.Packages = append(out.Packages, get)
+		}
+
+		out.Packages = append(out.Packages, rule.AttrStrings("install")...)
+	default:
+		return nil, false
+	}
+
+	if out.Module == "" {
+		return nil, false
+	}
+
+	return out, true
+}
+
+// goGetPath returns the go import path provided by the go_get rule.
+func goGetPath(rule please.Rule) string {
+	if rule.Kind() == "go_get_with_sources" {
+		if outs := rule.AttrStrings("outs"); len(outs) > 0 {
+			return outs[0]
+		}
+
+		return ""
+	}
+
+	get := strings.TrimSuffix(rule.AttrString("get"), "/...")
+	if get == "" {
+		if install := rule.AttrStrings("install"); len(install) > 0 {
+			sort.Strings(install)
+			get = strings.TrimSuffix(install[0], "/...")
+		}
+	}
+
+	return get
+}
+
+// canonicalThirdPartyRule returns the rule to keep of the rules providing the
+// same go module. It prefers the greatest semantic version, then a go_module
+// rule, then the rule in the package named after the module and finally the
+// first label.
+func canonicalThirdPartyRule(rules []*thirdPartyRule) *thirdPartyRule {
+	var versions []string
+	for _, rule := range rules {
+		versions = append(versions, rule.Version)
+	}
+
+	latest := maxVersion(versions)
+
+	rank := func(rule *thirdPartyRule) int {
+		var rank int
+
+		if latest != "" && rule.Version == latest {
+			rank += 4
+		}
+
+		if rule.Kind == "go_module" {
+			rank += 2
+		}
+
+		if strings.HasSuffix(rule.Path, "/"+rule.Module) {
+			rank++
+		}
+
+		return rank
+	}
+
+	canonical := rules[0]
+
+	for _, rule := range rules[1:] {
+		if rank(rule) > rank(canonical) {
+			canonical = rule
+		}
+	}
+
+	return canonical
+}
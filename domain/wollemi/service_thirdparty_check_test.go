@@ -0,0 +1,204 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/wollemi"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_ThirdpartyCheck(t *testing.T) {
+	NewServiceSuite(t).TestService_ThirdpartyCheck()
+}
+
+func (t *ServiceSuite) TestService_ThirdpartyCheck() {
+	type T = ServiceSuite
+
+	t.It("reports duplicate, conflicting and covered third party rules", func(t *T) {
+		t.MockThirdpartyCheck()
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyCheck(nil)
+		require.NoError(t, err)
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "debug" {
+				continue
+			}
+
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":  "warn",
+			"msg":    "duplicate third party module rules",
+			"module": "github.com/pkg/errors",
+			"rules": []string{
+				"//third_party/go:errors",
+				"//third_party/go:errors_get",
+			},
+			"canonical": "//third_party/go:errors",
+		}, {
+			"level":  "error",
+			"msg":    "conflicting third party module versions",
+			"module": "github.com/spf13/cobra",
+			"rules": []string{
+				"//third_party/go/github.com/spf13:cobra",
+				"//third_party/go:cobra",
+			},
+			"versions":  []string{"v1.0.0", "v1.2.0"},
+			"canonical": "//third_party/go/github.com/spf13:cobra",
+		}, {
+			"level":  "warn",
+			"msg":    "duplicate third party module rules",
+			"module": "gopkg.in/yaml.v2",
+			"rules": []string{
+				"//third_party/go:yaml",
+				"//third_party/go:yaml_v2",
+			},
+			"canonical": "//third_party/go:yaml",
+		}, {
+			"level":      "warn",
+			"msg":        "third party module covered by install entry",
+			"rule":       "//third_party/go:x_sys",
+			"module":     "golang.org/x/sys",
+			"covered_by": "//third_party/go:x_all",
+			"install":    "golang.org/x/...",
+			"canonical":  "//third_party/go:x_all",
+		}}, have)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyCheck([]string{"/outside/of/root"})
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockThirdpartyCheck() {
+	dirs := map[string][]os.FileInfo{
+		"third_party/go": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+			&FileInfo{FileName: "github.com", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"third_party/go/github.com": []os.FileInfo{
+			&FileInfo{FileName: "spf13", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"third_party/go/github.com/spf13": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+		},
+	}
+
+	files := map[string]*please.BuildFile{
+		"third_party/go/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "cobra"),
+					please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+					please.NewAssignExpr("=", "version", "v1.0.0"),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "yaml"),
+					please.NewAssignExpr("=", "module", "gopkg.in/yaml.v2"),
+					please.NewAssignExpr("=", "version", "v2.2.8"),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "yaml_v2"),
+					please.NewAssignExpr("=", "module", "gopkg.in/yaml.v2"),
+					please.NewAssignExpr("=", "version", "v2.2.8"),
+					please.NewAssignExpr("=", "install", []string{"."}),
+				}),
+				please.NewCallExpr("go_get", []please.Expr{
+					please.NewAssignExpr("=", "name", "x_all"),
+					please.NewAssignExpr("=", "get", "golang.org/x/..."),
+					please.NewAssignExpr("=", "revision", "97ca703d548d"),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "x_sys"),
+					please.NewAssignExpr("=", "module", "golang.org/x/sys"),
+					please.NewAssignExpr("=", "version", "v0.1.0"),
+					please.NewAssignExpr("=", "install", []string{"unix"}),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "cloud_go"),
+					please.NewAssignExpr("=", "module", "cloud.google.com/go"),
+					please.NewAssignExpr("=", "version", "v0.100.0"),
+					please.NewAssignExpr("=", "install", []string{"..."}),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "cloud_storage"),
+					please.NewAssignExpr("=", "module", "cloud.google.com/go/storage"),
+					please.NewAssignExpr("=", "version", "v1.20.0"),
+				}),
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "errors"),
+					please.NewAssignExpr("=", "module", "github.com/pkg/errors"),
+					please.NewAssignExpr("=", "version", "v0.9.1"),
+				}),
+				please.NewCallExpr("go_get", []please.Expr{
+					please.NewAssignExpr("=", "name", "errors_get"),
+					please.NewAssignExpr("=", "get", "github.com/pkg/errors"),
+					please.NewAssignExpr("=", "revision", "614d223910a1"),
+				}),
+				please.NewCallExpr("go_get", []please.Expr{
+					please.NewAssignExpr("=", "name", "mockgen"),
+					please.NewAssignExpr("=", "get", "github.com/golang/mock/mockgen"),
+					please.NewAssignExpr("=", "binary", &please.Ident{Name: "True"}),
+				}),
+			},
+		},
+		"third_party/go/github.com/spf13/BUILD.plz": &please.BuildFile{
+			Stmt: []please.Expr{
+				please.NewCallExpr("go_module", []please.Expr{
+					please.NewAssignExpr("=", "name", "cobra"),
+					please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+					please.NewAssignExpr("=", "version", "v1.2.0"),
+				}),
+			},
+		},
+	}
+
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) ([]os.FileInfo, error) {
+			infos, ok := dirs[path]
+			if !ok {
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			return infos, nil
+		})
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			buf.Reset()
+			buf.WriteString(path)
+
+			return nil
+		})
+
+	t.please.EXPECT().Parse(any, any).AnyTimes().
+		DoAndReturn(func(path string, buf []byte) (please.File, error) {
+			file, ok := files[path]
+			if !ok {
+				t.Errorf("unexpected call to please parse: %s", path)
+			}
+
+			file.Path = path
+
+			return file, nil
+		})
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
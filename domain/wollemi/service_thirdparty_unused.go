@@ -0,0 +1,221 @@
+package wollemi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// ThirdpartyUnused reports the install entries of the go_module rules under the
+// provided paths, or under the third party dirs when no paths are provided,
+// which install no package imported by the go source files of the repo. When
+// prune is set the unused entries are removed. Modules which third party rules
+// used by first party rules, directly or transitively, depend on are skipped
+// since the packages those rules need can not be known without their sources.
+func (this *Service) ThirdpartyUnused(prune bool, paths []string) error {
+	if err := this.validateAbsolutePaths(paths); err != nil {
+		return err
+	}
+
+	thirdPartyDirs := this.filesystem.Config(".").Merge(this.config).GetThirdPartyDirs()
+
+	if len(paths) == 0 {
+		for _, dir := range thirdPartyDirs {
+			paths = append(paths, filepath.Join(dir, "..."))
+		}
+	} else {
+		paths = this.normalizePaths(paths)
+	}
+
+	walk := make(chan *Directory, 1000)
+
+	if err := this.ReadDirs(walk, "..."); err != nil {
+		return fmt.Errorf("could not walk: %v", err)
+	}
+
+	imports := make(map[string]bool)
+	deps := make(map[string][]string)
+	thirdParty := make(map[string]bool)
+
+	var roots []string
+
+	var files []please.File
+
+	buf := bytes.NewBuffer(nil)
+
+	for dir := range walk {
+		if dir.HasGoFile {
+			if gopkg, err := this.golang.ImportDir(dir.Path, dir.GoFiles); err == nil {
+				for _, list := range [][]string{gopkg.Imports, gopkg.TestImports, gopkg.XTestImports} {
+					for _, path := range list {
+						imports[path] = true
+					}
+				}
+			} else if !noBuildableGoSources(err) {
+				this.log.WithError(err).
+					WithField("path", dir.Path).
+					Warn("could not build go import directory")
+			}
+		}
+
+		if len(dir.BuildFiles) == 0 {
+			continue
+		}
+
+		file := this.parseBuildFile(buf, dir.Path)
+		if file == nil {
+			continue
+		}
+
+		inThirdParty := inThirdPartyDir(dir.Path, thirdPartyDirs)
+
+		file.GetRules(func(rule please.Rule) {
+			label := fmt.Sprintf("//%s:%s", dir.Path, rule.Name())
+
+			for _, dep := range rule.AttrStrings("deps") {
+				if dep, ok := graphLabel(dir.Path, dep); ok {
+					deps[label] = append(deps[label], dep)
+				}
+			}
+
+			if inThirdParty {
+				thirdParty[label] = true
+			} else {
+				roots = append(roots, label)
+			}
+		})
+
+		if inRunPath(dir.Path, paths...) {
+			files = append(files, file)
+		}
+	}
+
+	needed := thirdPartyNeeded(roots, deps, thirdParty)
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].GetPath() < files[j].GetPath()
+	})
+
+	for _, file := range files {
+		path := filepath.Dir(file.GetPath())
+		log := this.log.WithField("path", path)
+
+		var pruned int
+
+		file.GetRules(func(rule please.Rule) {
+			if rule.Kind() != "go_module" {
+				return
+			}
+
+			log := log.WithField("rule", rule.Name())
+
+			if needed[fmt.Sprintf("//%s:%s", path, rule.Name())] {
+				log.Debug("skipped module needed by other third party rules")
+				return
+			}
+
+			module := rule.AttrString("module")
+			install := rule.AttrStrings("install")
+
+			if module == "" || len(install) == 0 {
+				return
+			}
+
+			var keep, unused []string
+
+			for _, entry := range install {
+				if isInstallImported(module, entry, imports) {
+					keep = append(keep, entry)
+				} else {
+					unused = append(unused, entry)
+				}
+			}
+
+			if len(unused) == 0 {
+				return
+			}
+
+			log = log.WithField("module", module).
+				WithField("install", unused)
+
+			switch {
+			case len(keep) == 0:
+				log.Warn("no installed package is imported")
+			case prune:
+				rule.SetAttr("install", please.Strings(keep...))
+				pruned++
+
+				log.Info("pruned unused install")
+			default:
+				log.Info("unused install")
+			}
+		})
+
+		if pruned > 0 {
+			if err := this.please.Write(file); err != nil {
+				log.WithError(err).Warn("could not write")
+			}
+		}
+	}
+
+	return nil
+}
+
+// thirdPartyNeeded returns the rules depended on by the third party rules which
+// are reachable from the first party root rules by following their deps.
+func thirdPartyNeeded(roots []string, deps map[string][]string, thirdParty map[string]bool) map[string]bool {
+	needed := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	for len(roots) > 0 {
+		label := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+
+		if visited[label] {
+			continue
+		}
+
+		visited[label] = true
+
+		for _, dep := range deps[label] {
+			if thirdParty[label] {
+				needed[dep] = true
+			}
+
+			roots = append(roots, dep)
+		}
+	}
+
+	return needed
+}
+
+// isInstallImported determines if one of the imports is a package installed
+// by the install entry of the module.
+func isInstallImported(module, entry string, imports map[string]bool) bool {
+	for imp := range imports {
+		if installsPackage(module, entry, imp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// installsPackage determines if the install entry of the module installs the
+// package. An entry ending in ... installs every package under it.
+func installsPackage(module, entry, pkg string) bool {
+	path := module
+	if install := filepath.Clean(strings.TrimSuffix(entry, "...")); install != "." {
+		path = filepath.Join(module, install)
+	}
+
+	if pkg == path {
+		return true
+	}
+
+	return strings.HasSuffix(entry, "...") && strings.HasPrefix(pkg, path+"/")
+}
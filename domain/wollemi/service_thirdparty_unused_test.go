@@ -0,0 +1,227 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/wollemi"
+	"github.com/tcncloud/wollemi/ports/golang"
+	"github.com/tcncloud/wollemi/testdata/expect"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_ThirdpartyUnused(t *testing.T) {
+	NewServiceSuite(t).TestService_ThirdpartyUnused()
+}
+
+func (t *ServiceSuite) TestService_ThirdpartyUnused() {
+	type T = ServiceSuite
+
+	t.It("lists install entries of go modules which are not imported", func(t *T) {
+		t.MockThirdpartyUnused()
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUnused(false, nil)
+		require.NoError(t, err)
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "debug" {
+				continue
+			}
+
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":   "info",
+			"msg":     "unused install",
+			"path":    "third_party/go",
+			"rule":    "cobra",
+			"module":  "github.com/spf13/cobra",
+			"install": []string{"doc"},
+		}, {
+			"level":   "warn",
+			"msg":     "no installed package is imported",
+			"path":    "third_party/go",
+			"rule":    "yaml",
+			"module":  "gopkg.in/yaml.v2",
+			"install": []string{"."},
+		}, {
+			"level":   "warn",
+			"msg":     "no installed package is imported",
+			"path":    "third_party/go",
+			"rule":    "viper",
+			"module":  "github.com/spf13/viper",
+			"install": []string{"."},
+		}}, have)
+	})
+
+	t.It("prunes install entries of go modules which are not imported", func(t *T) {
+		t.MockThirdpartyUnused()
+
+		t.please.EXPECT().Write(any).Times(1).Do(func(have please.File) {
+			expect.Equal(t, &please.BuildFile{
+				Path: "third_party/go/BUILD.plz",
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "cobra"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+						please.NewAssignExpr("=", "install", []string{"."}),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "pflag"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/pflag"),
+						please.NewAssignExpr("=", "install", []string{"..."}),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "x_sys"),
+						please.NewAssignExpr("=", "module", "golang.org/x/sys"),
+						please.NewAssignExpr("=", "install", []string{"unix", "windows"}),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "fsnotify"),
+						please.NewAssignExpr("=", "module", "github.com/fsnotify/fsnotify"),
+						please.NewAssignExpr("=", "deps", []string{":x_sys"}),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "yaml"),
+						please.NewAssignExpr("=", "module", "gopkg.in/yaml.v2"),
+						please.NewAssignExpr("=", "install", []string{"."}),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "viper"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/viper"),
+						please.NewAssignExpr("=", "install", []string{"."}),
+						please.NewAssignExpr("=", "deps", []string{":yaml"}),
+					}),
+				},
+			}, have)
+		})
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUnused(true, nil)
+		require.NoError(t, err)
+	})
+
+	t.It("returns an error if given an absolute path which is not under the repo root", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUnused(false, []string{"/outside/of/root"})
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockThirdpartyUnused() {
+	dirs := map[string][]os.FileInfo{
+		".": []os.FileInfo{
+			&FileInfo{FileName: "app", FileMode: os.ModeDir | 0755, FileIsDir: true},
+			&FileInfo{FileName: "third_party", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"app": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+			&FileInfo{FileName: "main.go", FileMode: os.FileMode(420)},
+		},
+		"third_party": []os.FileInfo{
+			&FileInfo{FileName: "go", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"third_party/go": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+		},
+	}
+
+	file := &please.BuildFile{
+		Path: "third_party/go/BUILD.plz",
+		Stmt: []please.Expr{
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "cobra"),
+				please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+				please.NewAssignExpr("=", "install", []string{".", "doc"}),
+			}),
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "pflag"),
+				please.NewAssignExpr("=", "module", "github.com/spf13/pflag"),
+				please.NewAssignExpr("=", "install", []string{"..."}),
+			}),
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "x_sys"),
+				please.NewAssignExpr("=", "module", "golang.org/x/sys"),
+				please.NewAssignExpr("=", "install", []string{"unix", "windows"}),
+			}),
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "fsnotify"),
+				please.NewAssignExpr("=", "module", "github.com/fsnotify/fsnotify"),
+				please.NewAssignExpr("=", "deps", []string{":x_sys"}),
+			}),
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "yaml"),
+				please.NewAssignExpr("=", "module", "gopkg.in/yaml.v2"),
+				please.NewAssignExpr("=", "install", []string{"."}),
+			}),
+			please.NewCallExpr("go_module", []please.Expr{
+				please.NewAssignExpr("=", "name", "viper"),
+				please.NewAssignExpr("=", "module", "github.com/spf13/viper"),
+				please.NewAssignExpr("=", "install", []string{"."}),
+				please.NewAssignExpr("=", "deps", []string{":yaml"}),
+			}),
+		},
+	}
+
+	app := &please.BuildFile{
+		Path: "app/BUILD.plz",
+		Stmt: []please.Expr{
+			please.NewCallExpr("go_binary", []please.Expr{
+				please.NewAssignExpr("=", "name", "app"),
+				please.NewAssignExpr("=", "srcs", []string{"main.go"}),
+				please.NewAssignExpr("=", "deps", []string{
+					"//third_party/go:cobra",
+					"//third_party/go:fsnotify",
+					"//third_party/go:pflag",
+				}),
+			}),
+		},
+	}
+
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) ([]os.FileInfo, error) {
+			infos, ok := dirs[path]
+			if !ok {
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			return infos, nil
+		})
+
+	t.golang.EXPECT().ImportDir("app", []string{"main.go"}).AnyTimes().
+		Return(&golang.Package{
+			GoFiles: []string{"main.go"},
+			Imports: []string{
+				"fmt",
+				"github.com/fsnotify/fsnotify",
+				"github.com/spf13/cobra",
+				"github.com/spf13/pflag/internal/flags",
+			},
+		}, nil)
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			buf.Reset()
+			buf.WriteString(path)
+
+			return nil
+		})
+
+	t.please.EXPECT().Parse("third_party/go/BUILD.plz", any).AnyTimes().Return(file, nil)
+
+	t.please.EXPECT().Parse("app/BUILD.plz", any).AnyTimes().Return(app, nil)
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
@@ -0,0 +1,277 @@
+package wollemi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// ThirdpartyUpgrade sets the version of every third party go rule of the
+// module under the third party dirs, matched as when resolving go imports. The
+// version attribute of go_module rules and the revision attribute of go_get
+// rules are rewritten. When the go.mod of the repo requires the module its
+// requirement is upgraded too, adding the go.sum hashes of the new version
+// found in the local module cache. The targets depending on each upgraded rule
+// are reported.
+func (this *Service) ThirdpartyUpgrade(mod, version string) error {
+	if !semver.IsValid(version) {
+		return fmt.Errorf("invalid version: %s", version)
+	}
+
+	// Checks the module path too, such as the major version suffix required by
+	// versions from v2 on.
+	if err := module.Check(mod, version); err != nil {
+		return fmt.Errorf("invalid module: %v", err)
+	}
+
+	var paths []string
+	for _, dir := range this.filesystem.Config(".").Merge(this.config).GetThirdPartyDirs() {
+		paths = append(paths, filepath.Join(dir, "..."))
+	}
+
+	rules, err := this.parseThirdPartyRules(paths)
+	if err != nil {
+		return err
+	}
+
+	var upgrade []*thirdPartyRule
+
+	for _, rule := range rules {
+		if rule.Module == mod || rule.Kind != "go_module" && strings.HasPrefix(rule.Module, mod+"/") {
+			upgrade = append(upgrade, rule)
+		}
+	}
+
+	if len(upgrade) == 0 {
+		return fmt.Errorf("no third party rules found for module: %s", mod)
+	}
+
+	graph, err := this.parseGraph("...")
+	if err != nil {
+		return err
+	}
+
+	dependents := make(map[string][]string)
+
+	for path, pkg := range graph.Packages {
+		for name, target := range pkg.Targets {
+			for _, dep := range target.Deps {
+				dependents[dep] = append(dependents[dep], fmt.Sprintf("//%s:%s", path, name))
+			}
+		}
+	}
+
+	var files []please.File
+
+	for _, rule := range upgrade {
+		attr := "version"
+		if rule.Kind != "go_module" {
+			attr = "revision"
+		}
+
+		log := this.log.WithField("rule", rule.Label).
+			WithField("module", rule.Module)
+
+		if rule.Version == version {
+			log.WithField("version", version).Debug("already at version")
+			continue
+		}
+
+		rule.Rule.SetAttr(attr, please.String(version))
+
+		if len(files) == 0 || files[len(files)-1] != rule.File {
+			files = append(files, rule.File)
+		}
+
+		affected := dependents[rule.Label]
+		sort.Strings(affected)
+
+		log.WithField("from", rule.Version).
+			WithField("to", version).
+			WithField("dependents", affected).
+			Info("upgraded third party rule")
+	}
+
+	for _, file := range files {
+		if err := this.please.Write(file); err != nil {
+			return fmt.Errorf("could not write %s: %v", file.GetPath(), err)
+		}
+	}
+
+	return this.upgradeGoMod(mod, version)
+}
+
+// upgradeGoMod upgrades the requirement of the module in the go.mod of the
+// repo, when it has one, and adds the go.sum hashes of the new version found
+// in the local module cache. Failing to upgrade a go.mod or go.sum which needs
+// upgrading, including when the module cache does not have the new version, is
+// an error.
+func (this *Service) upgradeGoMod(mod, version string) error {
+	buf := bytes.NewBuffer(nil)
+
+	if err := this.filesystem.ReadAll(buf, "go.mod"); err != nil {
+		if !os.IsNotExist(err) {
+			this.log.WithError(err).Warn("could not read go.mod")
+		}
+
+		return nil
+	}
+
+	file, err := modfile.Parse("go.mod", buf.Bytes(), nil)
+	if err != nil {
+		this.log.WithError(err).Warn("could not parse go.mod")
+		return nil
+	}
+
+	var required bool
+
+	for _, req := range file.Require {
+		if req.Mod.Path == mod {
+			required = req.Mod.Version != version
+		}
+	}
+
+	if !required {
+		return nil
+	}
+
+	log := this.log.WithField("module", mod).WithField("version", version)
+
+	// The hashes are found before writing go.mod so that it is never left
+	// requiring a version which go.sum does not have.
+	sums, err := this.goModuleSums(mod, version)
+	if err != nil {
+		return fmt.Errorf("could not find go.sum hashes in module cache, run go mod download: %v", err)
+	}
+
+	if err := file.AddRequire(mod, version); err != nil {
+		return fmt.Errorf("could not upgrade go.mod: %v", err)
+	}
+
+	file.Cleanup()
+
+	data, err := file.Format()
+	if err != nil {
+		return fmt.Errorf("could not format go.mod: %v", err)
+	}
+
+	if err := this.filesystem.WriteFile("go.mod", data, os.FileMode(0644)); err != nil {
+		return fmt.Errorf("could not write go.mod: %v", err)
+	}
+
+	log.Info("upgraded go.mod")
+
+	buf.Reset()
+
+	if err := this.filesystem.ReadAll(buf, "go.sum"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read go.sum: %v", err)
+	}
+
+	var lines []string
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = appendUniqString(lines, line)
+		}
+	}
+
+	for _, line := range sums {
+		lines = appendUniqString(lines, line)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lessGoSumLine(lines[i], lines[j])
+	})
+
+	data = []byte(strings.Join(lines, "\n") + "\n")
+
+	if err := this.filesystem.WriteFile("go.sum", data, os.FileMode(0644)); err != nil {
+		return fmt.Errorf("could not write go.sum: %v", err)
+	}
+
+	log.Info("upgraded go.sum")
+
+	return nil
+}
+
+// lessGoSumLine orders go.sum lines as the go command does, by module path
+// and then by version with the go.mod hash after the module hash.
+func lessGoSumLine(a, b string) bool {
+	x, y := strings.Fields(a), strings.Fields(b)
+	if len(x) < 2 || len(y) < 2 {
+		return a < b
+	}
+
+	if x[0] != y[0] {
+		return x[0] < y[0]
+	}
+
+	xv, xmod := strings.TrimSuffix(x[1], "/go.mod"), strings.HasSuffix(x[1], "/go.mod")
+	yv, ymod := strings.TrimSuffix(y[1], "/go.mod"), strings.HasSuffix(y[1], "/go.mod")
+
+	if c := semver.Compare(xv, yv); c != 0 {
+		return c < 0
+	}
+
+	return !xmod && ymod
+}
+
+// goModuleSums returns the go.sum lines of the module version from the
+// download cache of the local module cache.
+func (this *Service) goModuleSums(mod, version string) ([]string, error) {
+	cache := this.golang.GOMODCACHE()
+	if cache == "" {
+		return nil, fmt.Errorf("no module cache")
+	}
+
+	escaped, err := module.EscapePath(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(cache, "cache", "download", escaped, "@v", escapedVersion)
+
+	buf := bytes.NewBuffer(nil)
+
+	if err := this.filesystem.ReadAll(buf, base+".ziphash"); err != nil {
+		return nil, err
+	}
+
+	zipHash := strings.TrimSpace(buf.String())
+
+	modHash, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		buf := bytes.NewBuffer(nil)
+		if err := this.filesystem.ReadAll(buf, base+".mod"); err != nil {
+			return nil, err
+		}
+
+		return ioutil.NopCloser(buf), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("%s %s %s", mod, version, zipHash),
+		fmt.Sprintf("%s %s/go.mod %s", mod, version, modHash),
+	}, nil
+}
@@ -0,0 +1,291 @@
+package wollemi_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/wollemi"
+	"github.com/tcncloud/wollemi/testdata/expect"
+	"github.com/tcncloud/wollemi/testdata/please"
+)
+
+func TestService_ThirdpartyUpgrade(t *testing.T) {
+	NewServiceSuite(t).TestService_ThirdpartyUpgrade()
+}
+
+func (t *ServiceSuite) TestService_ThirdpartyUpgrade() {
+	type T = ServiceSuite
+
+	t.It("upgrades every third party rule of the module", func(t *T) {
+		t.MockThirdpartyUpgrade()
+
+		t.please.EXPECT().Write(any).Times(1).Do(func(have please.File) {
+			expect.Equal(t, &please.BuildFile{
+				Path: "third_party/go/BUILD.plz",
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "cobra"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+						please.NewAssignExpr("=", "version", "v1.3.0"),
+					}),
+					please.NewCallExpr("go_get", []please.Expr{
+						please.NewAssignExpr("=", "name", "cobra_doc"),
+						please.NewAssignExpr("=", "get", "github.com/spf13/cobra/doc"),
+						please.NewAssignExpr("=", "revision", "v1.3.0"),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "pflag"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/pflag"),
+						please.NewAssignExpr("=", "version", "v1.0.5"),
+					}),
+				},
+			}, have)
+		})
+
+		written := make(map[string]string)
+
+		t.filesystem.EXPECT().WriteFile(any, any, any).AnyTimes().
+			Do(func(path string, data []byte, mode os.FileMode) {
+				written[path] = string(data)
+			})
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "v1.3.0")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"go.mod": "module github.com/example\n" +
+				"\n" +
+				"go 1.17\n" +
+				"\n" +
+				"require (\n" +
+				"\tgithub.com/spf13/cobra v1.3.0\n" +
+				"\tgithub.com/spf13/pflag v1.0.5\n" +
+				")\n",
+			"go.sum": "github.com/spf13/cobra v1.0.0 h1:aaa=\n" +
+				"github.com/spf13/cobra v1.0.0/go.mod h1:bbb=\n" +
+				"github.com/spf13/cobra v1.3.0 h1:zip=\n" +
+				"github.com/spf13/cobra v1.3.0/go.mod h1:1l0Ry5zgKvJasoi3XT1TypsSe7PqH0Sj9dhYf7v3XqQ=\n" +
+				"github.com/spf13/pflag v1.0.5 h1:ccc=\n" +
+				"github.com/spf13/pflag v1.0.5/go.mod h1:ddd=\n",
+		}, written)
+
+		var have []map[string]interface{}
+		for _, entry := range t.logger.Lines() {
+			if entry["level"] == "debug" {
+				continue
+			}
+
+			delete(entry, "time")
+			have = append(have, entry)
+		}
+
+		assert.Equal(t, []map[string]interface{}{{
+			"level":      "info",
+			"msg":        "upgraded third party rule",
+			"rule":       "//third_party/go:cobra",
+			"module":     "github.com/spf13/cobra",
+			"from":       "v1.0.0",
+			"to":         "v1.3.0",
+			"dependents": []string{"//app:app"},
+		}, {
+			"level":      "info",
+			"msg":        "upgraded third party rule",
+			"rule":       "//third_party/go:cobra_doc",
+			"module":     "github.com/spf13/cobra/doc",
+			"from":       "v1.0.0",
+			"to":         "v1.3.0",
+			"dependents": []string{"//app:lib"},
+		}, {
+			"level":   "info",
+			"msg":     "upgraded go.mod",
+			"module":  "github.com/spf13/cobra",
+			"version": "v1.3.0",
+		}, {
+			"level":   "info",
+			"msg":     "upgraded go.sum",
+			"module":  "github.com/spf13/cobra",
+			"version": "v1.3.0",
+		}}, have)
+	})
+
+	t.It("returns an error if no third party rule provides the module", func(t *T) {
+		t.MockThirdpartyUpgrade()
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/viper", "v1.3.0")
+		assert.Error(t, err)
+	})
+
+	t.It("returns an error if the build file can not be written", func(t *T) {
+		t.MockThirdpartyUpgrade()
+
+		t.please.EXPECT().Write(any).Times(1).Return(fmt.Errorf("permission denied"))
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "v1.3.0")
+		assert.EqualError(t, err, "could not write third_party/go/BUILD.plz: permission denied")
+	})
+
+	t.It("returns an error if the go.mod can not be written", func(t *T) {
+		t.MockThirdpartyUpgrade()
+
+		t.please.EXPECT().Write(any).Times(1)
+
+		t.filesystem.EXPECT().WriteFile("go.mod", any, any).Times(1).Return(fmt.Errorf("permission denied"))
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "v1.3.0")
+		assert.EqualError(t, err, "could not write go.mod: permission denied")
+	})
+
+	t.It("returns an error without writing go.mod if the module cache does not have the version", func(t *T) {
+		t.MockThirdpartyUpgrade()
+
+		t.please.EXPECT().Write(any).Times(1)
+
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "v1.2.0")
+		assert.EqualError(t, err, "could not find go.sum hashes in module cache, run go mod download: file does not exist")
+	})
+
+	t.It("returns an error if the version does not match the major version of the module", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "v2.0.0")
+		assert.Error(t, err)
+	})
+
+	t.It("returns an error if given an invalid version", func(t *T) {
+		err := t.New(root, wd, gosrc, gopkg).ThirdpartyUpgrade("github.com/spf13/cobra", "latest")
+		assert.Error(t, err)
+	})
+}
+
+func (t *ServiceSuite) MockThirdpartyUpgrade() {
+	dirs := map[string][]os.FileInfo{
+		".": []os.FileInfo{
+			&FileInfo{FileName: "app", FileMode: os.ModeDir | 0755, FileIsDir: true},
+			&FileInfo{FileName: "third_party", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"app": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+		},
+		"third_party": []os.FileInfo{
+			&FileInfo{FileName: "go", FileMode: os.ModeDir | 0755, FileIsDir: true},
+		},
+		"third_party/go": []os.FileInfo{
+			&FileInfo{FileName: "BUILD.plz", FileMode: os.FileMode(420)},
+		},
+	}
+
+	contents := map[string]string{
+		"go.mod": "module github.com/example\n" +
+			"\n" +
+			"go 1.17\n" +
+			"\n" +
+			"require (\n" +
+			"\tgithub.com/spf13/cobra v1.0.0\n" +
+			"\tgithub.com/spf13/pflag v1.0.5\n" +
+			")\n",
+		"go.sum": "github.com/spf13/cobra v1.0.0 h1:aaa=\n" +
+			"github.com/spf13/cobra v1.0.0/go.mod h1:bbb=\n" +
+			"github.com/spf13/pflag v1.0.5 h1:ccc=\n" +
+			"github.com/spf13/pflag v1.0.5/go.mod h1:ddd=\n",
+		"/go/pkg/mod/cache/download/github.com/spf13/cobra/@v/v1.3.0.ziphash": "h1:zip=\n",
+		"/go/pkg/mod/cache/download/github.com/spf13/cobra/@v/v1.3.0.mod":     "module github.com/spf13/cobra\n",
+	}
+
+	files := map[string]func() *please.BuildFile{
+		"app/BUILD.plz": func() *please.BuildFile {
+			return &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_binary", []please.Expr{
+						please.NewAssignExpr("=", "name", "app"),
+						please.NewAssignExpr("=", "deps", []string{"//third_party/go:cobra"}),
+					}),
+					please.NewCallExpr("go_library", []please.Expr{
+						please.NewAssignExpr("=", "name", "lib"),
+						please.NewAssignExpr("=", "deps", []string{
+							"//third_party/go:cobra_doc",
+							"//third_party/go:pflag",
+						}),
+					}),
+				},
+			}
+		},
+		"third_party/go/BUILD.plz": func() *please.BuildFile {
+			return &please.BuildFile{
+				Stmt: []please.Expr{
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "cobra"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/cobra"),
+						please.NewAssignExpr("=", "version", "v1.0.0"),
+					}),
+					please.NewCallExpr("go_get", []please.Expr{
+						please.NewAssignExpr("=", "name", "cobra_doc"),
+						please.NewAssignExpr("=", "get", "github.com/spf13/cobra/doc"),
+						please.NewAssignExpr("=", "revision", "v1.0.0"),
+					}),
+					please.NewCallExpr("go_module", []please.Expr{
+						please.NewAssignExpr("=", "name", "pflag"),
+						please.NewAssignExpr("=", "module", "github.com/spf13/pflag"),
+						please.NewAssignExpr("=", "version", "v1.0.5"),
+					}),
+				},
+			}
+		},
+	}
+
+	t.filesystem.EXPECT().ReadDir(any).AnyTimes().
+		DoAndReturn(func(path string) ([]os.FileInfo, error) {
+			infos, ok := dirs[path]
+			if !ok {
+				t.Errorf("unexpected call to filesystem read dir: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			return infos, nil
+		})
+
+	t.filesystem.EXPECT().ReadAll(any, any).AnyTimes().
+		DoAndReturn(func(buf *bytes.Buffer, path string) error {
+			buf.Reset()
+
+			if _, ok := files[path]; ok {
+				buf.WriteString(path)
+				return nil
+			}
+
+			content, ok := contents[path]
+			if !ok {
+				return os.ErrNotExist
+			}
+
+			buf.WriteString(content)
+
+			return nil
+		})
+
+	t.please.EXPECT().Parse(any, any).AnyTimes().
+		DoAndReturn(func(path string, buf []byte) (please.File, error) {
+			file, ok := files[path]
+			if !ok {
+				t.Errorf("unexpected call to please parse: %s", path)
+				return nil, os.ErrNotExist
+			}
+
+			build := file()
+			build.Path = path
+
+			return build, nil
+		})
+
+	t.golang.EXPECT().GOMODCACHE().AnyTimes().Return("/go/pkg/mod")
+
+	t.filesystem.EXPECT().Stat(any).AnyTimes().Return(nil, os.ErrNotExist)
+
+	t.filesystem.EXPECT().ConfigFiles(any).AnyTimes().Return(nil)
+
+	t.filesystem.EXPECT().Config(any).AnyTimes().Return(wollemi.Config{})
+
+	t.please.EXPECT().Config(any).AnyTimes().Return(please.Config{}, nil)
+}
@@ -42,6 +42,18 @@ func inRunPath(targetPath string, run ...string) bool {
 	return false
 }
 
+// inThirdPartyDir determines if the path is inside one of the third party
+// directories.
+func inThirdPartyDir(path string, thirdPartyDirs []string) bool {
+	for _, dir := range thirdPartyDirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // nonBlockingSend ensures a non blocking send of directory to channel. It will
 // first attempt to send as normal on a select but if that blocks as a last
 // resort it will send inside of a goroutine. This is not expected to happen
@@ -54,12 +66,21 @@ func nonBlockingSend(ch chan *Directory, dir *Directory) {
 	}
 }
 
-func isBuildFile(name string) bool {
-	for _, want := range []string{"BUILD.plz", "BUILD"} {
-		if name == want {
-			return true
-		}
+// defaultBuildFileNames are the build file names recognised when the please
+// config does not define any.
+var defaultBuildFileNames = []string{"BUILD.plz", "BUILD"}
+
+// buildFileNames returns the build file names configured by the please config
+// in order of preference.
+func (this *Service) buildFileNames() []string {
+	if names := this.pleaseConfig().Parse.BuildFileName; len(names) > 0 {
+		return names
 	}
 
-	return false
+	return defaultBuildFileNames
+}
+
+// isBuildFile determines if the file name is one of the build file names.
+func (this *Service) isBuildFile(name string) bool {
+	return inStrings(this.buildFileNames(), name)
 }
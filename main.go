@@ -16,6 +16,7 @@ import (
 	"github.com/tcncloud/wollemi/domain/wollemi"
 	"github.com/tcncloud/wollemi/ports/ctl"
 	"github.com/tcncloud/wollemi/ports/logging"
+	wollemiport "github.com/tcncloud/wollemi/ports/wollemi"
 )
 
 func main() {
@@ -100,8 +101,26 @@ func (app *Application) Wollemi() (ctl.Wollemi, error) {
 	pleaseCtl := please.NewCtl()
 
 	config, err := pleaseCtl.Config(filepath.Join(root, ".plzconfig"))
-	if err == nil && config.Go.ImportPath != "" {
-		gopkg = config.Go.ImportPath
+	if err == nil {
+		if config.Go.ImportPath != "" {
+			gopkg = config.Go.ImportPath
+		}
+
+		base, err := wollemiport.PleaseConfig(config.Wollemi)
+		if err != nil {
+			log.WithError(err).
+				WithField("file", ".plzconfig").
+				Warn("invalid wollemi config")
+		} else {
+			filesystem.SetBaseConfig(wollemiport.ConfigFile{
+				Path:   ".plzconfig",
+				Config: base,
+			})
+		}
+	} else {
+		log.WithError(err).
+			WithField("file", ".plzconfig").
+			Warn("could not read please config")
 	}
 
 	bazel := bazel.NewBuilder(log, pleaseCtl, filesystem)
@@ -11,11 +11,18 @@ type Application interface {
 }
 
 type Wollemi interface {
+	BuildfilesMerge([]string) error
+	ConfigShow(string) (wollemi.ConfigProvenance, error)
+	ConfigValidate([]string) error
 	Format(wollemi.Config, []string) error
 	GoFormat(wollemi.Config, []string) error
 	GoPkgPath(...string) string
 	GoSrcPath(...string) string
 	SymlinkList(string, bool, bool, []string, []string) error
 	SymlinkGoPath(bool, []string) error
-	RulesUnused(bool, []string, []string, []string) error
+	RulesUnused(bool, bool, bool, []string, []string, []string, []string) error
+	RulesVisibility(bool, []string, []string) error
+	ThirdpartyUnused(bool, []string) error
+	ThirdpartyCheck([]string) error
+	ThirdpartyUpgrade(string, string) error
 }
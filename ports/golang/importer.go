@@ -4,6 +4,7 @@ type Importer interface {
 	ImportDir(string, []string) (*Package, error)
 	IsGoroot(string) bool
 	GOPATH() string
+	GOMODCACHE() string
 }
 
 type Package struct {
@@ -4,4 +4,22 @@ type Config struct {
 	Go struct {
 		ImportPath string
 	}
+	Parse struct {
+		BlacklistDirs []string
+		BuildFileName []string
+	}
+	Wollemi Wollemi
+}
+
+// Wollemi is the [wollemi] section of a .plzconfig file. Multi valued keys are
+// given once per value.
+type Wollemi struct {
+	DefaultVisibility         string
+	AllowUnresolvedDependency *bool
+	ExplicitSources           *bool
+	Create                    []string
+	Manage                    []string
+	Mapped                    []string
+	ThirdPartyDir             []string
+	Ignore                    []string
 }
@@ -5,5 +5,7 @@ type File interface {
 	GetRules(func(Rule))
 	GetRule(string) Rule
 	SetRule(Rule)
+	AddRule(Rule)
 	DelRule(string) bool
+	NumOtherStmts() int
 }
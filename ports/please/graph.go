@@ -17,4 +17,6 @@ type GraphTarget struct {
 	Requires []string `json:"requires,omitempty"`
 	Hash     string   `json:"hash,omitempty"`
 	Binary   bool     `json:"binary,omitempty"`
+	Test     bool     `json:"test,omitempty"`
+	TestOnly bool     `json:"test_only,omitempty"`
 }
@@ -2,7 +2,9 @@ package wollemi
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/tcncloud/wollemi/domain/optional"
 )
@@ -10,11 +12,15 @@ import (
 func Bool(value bool) *bool { return &value }
 
 type Config struct {
+	Extends                   []string          `json:"extends,omitempty"`
 	Gofmt                     Gofmt             `json:"gofmt,omitempty"`
 	DefaultVisibility         string            `json:"default_visibility,omitempty"`
 	KnownDependency           map[string]string `json:"known_dependency,omitempty"`
 	AllowUnresolvedDependency *optional.Bool    `json:"allow_unresolved_dependency,omitempty"`
 	ExplicitSources           *optional.Bool    `json:"explicit_sources,omitempty"`
+	ThirdPartyDirs            []string          `json:"third_party_dirs,omitempty"`
+	Ignore                    []string          `json:"ignore,omitempty"`
+	Unused                    Unused            `json:"unused,omitempty"`
 }
 
 func (Config) String() string {
@@ -22,10 +28,28 @@ func (Config) String() string {
 }
 
 type Gofmt struct {
-	Rewrite *bool       `json:"rewrite,omitempty"`
-	Create  gofmtCreate `json:"create,omitempty"`
-	Manage  gofmtManage `json:"manage,omitempty"`
-	Mapped  gofmtMapped `json:"mapped,omitempty"`
+	Rewrite  *bool                             `json:"rewrite,omitempty"`
+	Create   gofmtCreate                       `json:"create"`
+	Manage   gofmtManage                       `json:"manage"`
+	Mapped   gofmtMapped                       `json:"mapped,omitempty"`
+	Template map[string]map[string]interface{} `json:"template,omitempty"`
+	Naming   Naming                            `json:"naming,omitempty"`
+
+	WidenVisibility *bool `json:"widen_visibility,omitempty"`
+	InferTestOnly   *bool `json:"infer_test_only,omitempty"`
+	InstallMissing  *bool `json:"install_missing,omitempty"`
+	FixUnresolved   *bool `json:"fix_unresolved,omitempty"`
+}
+
+// Naming contains the name patterns of rules created by gofmt. A pattern may
+// contain {dir} which is replaced by the directory name and {pkg} which is
+// replaced by the go package name.
+type Naming struct {
+	GoBinary     string `json:"go_binary,omitempty"`
+	GoLibrary    string `json:"go_library,omitempty"`
+	GoTest       string `json:"go_test,omitempty"`
+	InternalTest string `json:"internal_test,omitempty"`
+	ExternalTest string `json:"external_test,omitempty"`
 }
 
 func (gofmt *Gofmt) GetRewrite() bool {
@@ -36,6 +60,46 @@ func (gofmt *Gofmt) GetRewrite() bool {
 	return true
 }
 
+// GetWidenVisibility determines if gofmt widens the visibility of dependencies
+// which are not visible to the rules depending on them.
+func (gofmt *Gofmt) GetWidenVisibility() bool {
+	if gofmt != nil && gofmt.WidenVisibility != nil {
+		return *gofmt.WidenVisibility
+	}
+
+	return false
+}
+
+// GetInferTestOnly determines if gofmt marks libraries depended on only by
+// tests as test only.
+func (gofmt *Gofmt) GetInferTestOnly() bool {
+	if gofmt != nil && gofmt.InferTestOnly != nil {
+		return *gofmt.InferTestOnly
+	}
+
+	return false
+}
+
+// GetInstallMissing determines if gofmt adds imported packages missing from
+// the install list of the go_module rule of their module.
+func (gofmt *Gofmt) GetInstallMissing() bool {
+	if gofmt != nil && gofmt.InstallMissing != nil {
+		return *gofmt.InstallMissing
+	}
+
+	return false
+}
+
+// GetFixUnresolved determines if gofmt writes go_module rules, found in the
+// local module cache, for unresolved imports rather than suggesting them.
+func (gofmt *Gofmt) GetFixUnresolved() bool {
+	if gofmt != nil && gofmt.FixUnresolved != nil {
+		return *gofmt.FixUnresolved
+	}
+
+	return false
+}
+
 func (gofmt *Gofmt) GetCreate() []string {
 	if gofmt != nil && gofmt.Create != nil {
 		return gofmt.Create
@@ -68,8 +132,136 @@ func (gofmt *Gofmt) GetMapped(kind string) string {
 	return kind
 }
 
+// GetTemplate returns the default attributes of rules of the provided kind
+// created by gofmt.
+func (gofmt *Gofmt) GetTemplate(kind string) map[string]interface{} {
+	if gofmt != nil {
+		return gofmt.Template[kind]
+	}
+
+	return nil
+}
+
+// GetName returns the name of a rule created by gofmt in the provided directory
+// for the go package. The kind is one of go_binary, go_library, go_test,
+// internal_test or external_test.
+func (gofmt *Gofmt) GetName(kind, dir, pkg string) string {
+	var naming Naming
+	if gofmt != nil {
+		naming = gofmt.Naming
+	}
+
+	var pattern string
+
+	switch kind {
+	case "go_binary":
+		pattern = naming.GoBinary
+	case "go_library":
+		pattern = naming.GoLibrary
+	case "go_test":
+		pattern = naming.GoTest
+	case "internal_test":
+		pattern = naming.InternalTest
+	case "external_test":
+		pattern = naming.ExternalTest
+	}
+
+	if pattern == "" {
+		switch kind {
+		case "go_binary", "go_library":
+			pattern = "{dir}"
+		case "go_test":
+			pattern = "test"
+		default:
+			pattern = kind
+		}
+	}
+
+	if pkg == "" {
+		pkg = dir
+	}
+
+	return strings.NewReplacer("{dir}", dir, "{pkg}", pkg).Replace(pattern)
+}
+
+// Unused contains the conventions used to find unused rules. Kinds are the
+// rule kinds checked by default. Sub targets list, by rule kind, the names of
+// the hidden rules created alongside rules of that kind which other rules may
+// depend on instead, where {name} is replaced by the rule name.
+type Unused struct {
+	Kinds      []string            `json:"kinds,omitempty"`
+	SubTargets map[string][]string `json:"sub_targets,omitempty"`
+}
+
+// defaultUnusedSubTargets are the sub targets of the rule kinds which please
+// and its standard build definitions expose.
+var defaultUnusedSubTargets = map[string][]string{
+	"grpc_library": []string{
+		"_{name}#proto",
+		"_{name}#go",
+		"_{name}#java",
+		"_{name}#py",
+		"_{name}#ts",
+	},
+	"pip_library": []string{
+		"_{name}#wheel",
+	},
+}
+
+func (unused *Unused) GetKinds() []string {
+	if unused != nil && unused.Kinds != nil {
+		return unused.Kinds
+	}
+
+	return []string{
+		"export_file",
+		"filegroup",
+		"genrule",
+		"go_get",
+		"go_get_with_sources",
+		"go_library",
+		"go_mock",
+		"go_module",
+		"grpc_library",
+		"pip_library",
+	}
+}
+
+// GetSubTargets returns the names of the sub targets of the named rule of the
+// provided kind.
+func (unused *Unused) GetSubTargets(kind, name string) []string {
+	patterns, ok := defaultUnusedSubTargets[kind]
+
+	if unused != nil {
+		if v, found := unused.SubTargets[kind]; found {
+			patterns = v
+			ok = true
+		}
+	}
+
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		names = append(names, strings.ReplaceAll(pattern, "{name}", name))
+	}
+
+	return names
+}
+
+func (this Config) GetThirdPartyDirs() []string {
+	if this.ThirdPartyDirs != nil {
+		return this.ThirdPartyDirs
+	}
+
+	return []string{"third_party/go"}
+}
+
 func (this Config) Merge(that Config) Config {
 	merge := this
+	merge.Extends = nil // extended configs are merged before the config itself
 
 	if that.DefaultVisibility != "" {
 		merge.DefaultVisibility = that.DefaultVisibility
@@ -106,10 +298,50 @@ func (this Config) Merge(that Config) Config {
 		merge.ExplicitSources = v
 	}
 
+	if len(that.Ignore) > 0 {
+		merge.Ignore = append(this.Ignore[:len(this.Ignore):len(this.Ignore)], that.Ignore...)
+	}
+
+	if v := that.ThirdPartyDirs; v != nil {
+		merge.ThirdPartyDirs = v
+	}
+
+	if v := that.Unused.Kinds; v != nil {
+		merge.Unused.Kinds = v
+	}
+
+	if len(this.Unused.SubTargets) > 0 || len(that.Unused.SubTargets) > 0 {
+		merge.Unused.SubTargets = make(map[string][]string)
+
+		for kind, names := range this.Unused.SubTargets {
+			merge.Unused.SubTargets[kind] = names
+		}
+
+		for kind, names := range that.Unused.SubTargets {
+			merge.Unused.SubTargets[kind] = names
+		}
+	}
+
 	if v := that.Gofmt.Rewrite; v != nil {
 		merge.Gofmt.Rewrite = v
 	}
 
+	if v := that.Gofmt.WidenVisibility; v != nil {
+		merge.Gofmt.WidenVisibility = v
+	}
+
+	if v := that.Gofmt.InferTestOnly; v != nil {
+		merge.Gofmt.InferTestOnly = v
+	}
+
+	if v := that.Gofmt.InstallMissing; v != nil {
+		merge.Gofmt.InstallMissing = v
+	}
+
+	if v := that.Gofmt.FixUnresolved; v != nil {
+		merge.Gofmt.FixUnresolved = v
+	}
+
 	if v := that.Gofmt.Create; v != nil {
 		merge.Gofmt.Create = v
 	}
@@ -122,6 +354,44 @@ func (this Config) Merge(that Config) Config {
 		merge.Gofmt.Mapped = v
 	}
 
+	for _, x := range []struct {
+		Merge *string
+		That  string
+	}{
+		{&merge.Gofmt.Naming.GoBinary, that.Gofmt.Naming.GoBinary},
+		{&merge.Gofmt.Naming.GoLibrary, that.Gofmt.Naming.GoLibrary},
+		{&merge.Gofmt.Naming.GoTest, that.Gofmt.Naming.GoTest},
+		{&merge.Gofmt.Naming.InternalTest, that.Gofmt.Naming.InternalTest},
+		{&merge.Gofmt.Naming.ExternalTest, that.Gofmt.Naming.ExternalTest},
+	} {
+		if x.That != "" {
+			*x.Merge = x.That
+		}
+	}
+
+	if len(this.Gofmt.Template) > 0 || len(that.Gofmt.Template) > 0 {
+		merge.Gofmt.Template = make(map[string]map[string]interface{})
+
+		for _, template := range []map[string]map[string]interface{}{
+			this.Gofmt.Template,
+			that.Gofmt.Template,
+		} {
+			for kind, attrs := range template {
+				if merge.Gofmt.Template[kind] == nil {
+					merge.Gofmt.Template[kind] = make(map[string]interface{})
+				}
+
+				for key, value := range attrs {
+					if value == nil { // null removes an inherited attribute
+						delete(merge.Gofmt.Template[kind], key)
+					} else {
+						merge.Gofmt.Template[kind][key] = value
+					}
+				}
+			}
+		}
+	}
+
 	return merge
 }
 
@@ -130,17 +400,23 @@ type gofmtCreate []string
 func (list *gofmtCreate) UnmarshalJSON(buf []byte) error {
 	err := json.Unmarshal(buf, (*[]string)(list))
 	if err != nil {
-		s, err := strconv.Unquote(string(buf))
-		if err == nil {
+		s, unquoteErr := strconv.Unquote(string(buf))
+		if unquoteErr == nil {
 			switch s {
 			case "on", "default":
 				*list = (*Gofmt)(nil).GetCreate()
+				err = nil // recover
 			case "off":
 				*list = []string{}
+				err = nil // recover
 			}
 		}
 	}
 
+	if err != nil {
+		return fmt.Errorf(`expected list of rule kinds or one of "on", "default", "off"`)
+	}
+
 	return nil
 }
 
@@ -179,6 +455,10 @@ func (list *gofmtManage) UnmarshalJSON(buf []byte) error {
 		*list = expand
 	}
 
+	if err != nil {
+		return fmt.Errorf(`expected list of rule kinds or one of "on", "default", "off"`)
+	}
+
 	return nil
 }
 
@@ -205,7 +485,11 @@ func (mapped *gofmtMapped) UnmarshalJSON(buf []byte) error {
 		(*mapped)[k] = v
 	}
 
-	return err
+	if err != nil {
+		return fmt.Errorf(`expected map of rule kinds or "none"`)
+	}
+
+	return nil
 }
 
 func inStrings(from []string, value string) bool {
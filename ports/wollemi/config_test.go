@@ -216,6 +216,63 @@ func TestConfig_Merge(t *testing.T) {
 		Want: wollemi.Config{
 			AllowUnresolvedDependency: optional.BoolValue(true),
 		},
+	}, {
+		Name: "merged third_party_dirs is rhs when rhs set",
+		Lhs: wollemi.Config{
+			ThirdPartyDirs: []string{"third_party/go"},
+		},
+		Rhs: wollemi.Config{
+			ThirdPartyDirs: []string{"vendor/go", "third_party/go"},
+		},
+		Want: wollemi.Config{
+			ThirdPartyDirs: []string{"vendor/go", "third_party/go"},
+		},
+	}, {
+		Name: "merged extends is always empty",
+		Lhs: wollemi.Config{
+			Extends: []string{"shared/lhs.json"},
+		},
+		Rhs: wollemi.Config{
+			Extends: []string{"shared/rhs.json"},
+		},
+		Want: wollemi.Config{},
+	}, {
+		Name: "merged gofmt template is each rhs kind attribute applied to lhs",
+		Lhs: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Template: map[string]map[string]interface{}{
+					"go_test":    {"size": "medium", "labels": []interface{}{"unit"}},
+					"go_library": {"test_only": true},
+				},
+			},
+		},
+		Rhs: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Template: map[string]map[string]interface{}{
+					"go_test":    {"size": "large", "flaky": true},
+					"go_library": {"test_only": nil},
+				},
+			},
+		},
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Template: map[string]map[string]interface{}{
+					"go_test":    {"size": "large", "flaky": true, "labels": []interface{}{"unit"}},
+					"go_library": {},
+				},
+			},
+		},
+	}, {
+		Name: "merged ignore is rhs patterns appended to lhs patterns",
+		Lhs: wollemi.Config{
+			Ignore: []string{"vendor", "gen"},
+		},
+		Rhs: wollemi.Config{
+			Ignore: []string{"!app/gen"},
+		},
+		Want: wollemi.Config{
+			Ignore: []string{"vendor", "gen", "!app/gen"},
+		},
 	}, {
 		Name: "merged known_dependency is all key values from rhs applied to lhs",
 		Lhs: wollemi.Config{
@@ -277,9 +334,108 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		Name: "merged unused sub_targets is all kinds from rhs applied to lhs",
+		Lhs: wollemi.Config{
+			Unused: wollemi.Unused{
+				Kinds: []string{"go_library"},
+				SubTargets: map[string][]string{
+					"go_service":  []string{"_{name}#lib"},
+					"pip_library": []string{"_{name}#wheel"},
+				},
+			},
+		},
+		Rhs: wollemi.Config{
+			Unused: wollemi.Unused{
+				SubTargets: map[string][]string{
+					"pip_library": []string{},
+				},
+			},
+		},
+		Want: wollemi.Config{
+			Unused: wollemi.Unused{
+				Kinds: []string{"go_library"},
+				SubTargets: map[string][]string{
+					"go_service":  []string{"_{name}#lib"},
+					"pip_library": []string{},
+				},
+			},
+		},
 	}} {
 		t.Run(tt.Name, func(t *testing.T) {
 			require.Equal(t, tt.Want, tt.Lhs.Merge(tt.Rhs))
 		})
 	}
 }
+
+func TestGofmt_GetName(t *testing.T) {
+	for _, tt := range []struct {
+		Name   string
+		Naming wollemi.Naming
+		Kind   string
+		Pkg    string
+		Want   string
+	}{{
+		Name: "library defaults to directory name",
+		Kind: "go_library",
+		Pkg:  "routes",
+		Want: "server",
+	}, {
+		Name: "test defaults to test",
+		Kind: "go_test",
+		Want: "test",
+	}, {
+		Name: "external test defaults to external_test",
+		Kind: "external_test",
+		Want: "external_test",
+	}, {
+		Name:   "expands package name",
+		Naming: wollemi.Naming{GoLibrary: "{pkg}_lib"},
+		Kind:   "go_library",
+		Pkg:    "routes",
+		Want:   "routes_lib",
+	}, {
+		Name:   "expands directory name when package name unknown",
+		Naming: wollemi.Naming{InternalTest: "{pkg}_internal_test"},
+		Kind:   "internal_test",
+		Want:   "server_internal_test",
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			gofmt := &wollemi.Gofmt{Naming: tt.Naming}
+
+			require.Equal(t, tt.Want, gofmt.GetName(tt.Kind, "server", tt.Pkg))
+		})
+	}
+}
+
+func TestUnused_GetSubTargets(t *testing.T) {
+	for _, tt := range []struct {
+		Name       string
+		SubTargets map[string][]string
+		Kind       string
+		Want       []string
+	}{{
+		Name: "defaults to the grpc_library sub targets",
+		Kind: "grpc_library",
+		Want: []string{"_rpc#proto", "_rpc#go", "_rpc#java", "_rpc#py", "_rpc#ts"},
+	}, {
+		Name: "defaults to no sub targets for other kinds",
+		Kind: "go_library",
+	}, {
+		Name:       "expands configured sub targets",
+		SubTargets: map[string][]string{"go_service": []string{"_{name}#lib"}},
+		Kind:       "go_service",
+		Want:       []string{"_rpc#lib"},
+	}, {
+		Name:       "configured sub targets replace the default",
+		SubTargets: map[string][]string{"grpc_library": []string{"_{name}#go"}},
+		Kind:       "grpc_library",
+		Want:       []string{"_rpc#go"},
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			unused := &wollemi.Unused{SubTargets: tt.SubTargets}
+
+			require.Equal(t, tt.Want, unused.GetSubTargets(tt.Kind, "rpc"))
+		})
+	}
+}
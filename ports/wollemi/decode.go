@@ -0,0 +1,181 @@
+package wollemi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigError describes an invalid key or value found while strictly decoding
+// a config file.
+type ConfigError struct {
+	Line   int
+	Column int
+	Key    string
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+	}
+
+	return fmt.Sprintf("%d:%d: %s: %v", e.Line, e.Column, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Unmarshal strictly decodes json config data. Unlike json.Unmarshal unknown
+// keys and invalid values are reported as a *ConfigError which locates the
+// offending key.
+func Unmarshal(data []byte, config *Config) error {
+	d := &decoder{data: data}
+
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		if e, ok := err.(*json.SyntaxError); ok {
+			return d.errorf(e.Offset-1, nil, "%v", err) // offset is past the error
+		}
+
+		return err
+	}
+
+	if err := d.validate(0, data, reflect.TypeOf(config).Elem(), nil); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, config)
+}
+
+// ignoredKeys are top level keys which are allowed in a config file even
+// though they do not configure anything.
+var ignoredKeys = []string{"$schema"}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+type decoder struct {
+	data []byte
+}
+
+func (d *decoder) validate(offset int64, raw []byte, t reflect.Type, path []string) error {
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return d.check(offset, raw, t, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return d.validate(offset, raw, t.Elem(), path)
+	case reflect.Struct:
+		return d.object(offset, raw, path, func(key string) (reflect.Type, bool) {
+			if len(path) == 0 && inStrings(ignoredKeys, key) {
+				return nil, true
+			}
+
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+				if name == key {
+					return field.Type, true
+				}
+			}
+
+			return nil, false
+		})
+	case reflect.Map:
+		return d.object(offset, raw, path, func(string) (reflect.Type, bool) {
+			return t.Elem(), true
+		})
+	default:
+		return d.check(offset, raw, t, path)
+	}
+}
+
+// object validates every key value pair of the json object in raw. The field
+// func returns the type expected for the value of a key and false when the key
+// is unknown. A nil type means the value is not validated.
+func (d *decoder) object(offset int64, raw []byte, path []string, field func(string) (reflect.Type, bool)) error {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return d.errorf(offset+dec.InputOffset(), path, "%v", err)
+	}
+
+	if tok != json.Delim('{') {
+		return d.errorf(offset, path, "expected object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return d.errorf(offset+dec.InputOffset(), path, "%v", err)
+		}
+
+		key := tok.(string)
+		at := offset + dec.InputOffset()
+		path := append(path[:len(path):len(path)], key)
+
+		t, ok := field(key)
+		if !ok {
+			return d.errorf(at-int64(len(key))-2, path, "unknown key")
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return d.errorf(at, path, "%v", err)
+		}
+
+		if t == nil {
+			continue
+		}
+
+		start := offset + dec.InputOffset() - int64(len(value))
+		if err := d.validate(start, value, t, path); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return d.errorf(offset+dec.InputOffset(), path, "%v", err)
+	}
+
+	return nil
+}
+
+// check validates raw by decoding it into a new value of the provided type.
+func (d *decoder) check(offset int64, raw []byte, t reflect.Type, path []string) error {
+	err := json.Unmarshal(raw, reflect.New(t).Interface())
+
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *json.UnmarshalTypeError:
+		return d.errorf(offset, path, "cannot use %s as %s", e.Value, e.Type)
+	default:
+		return d.errorf(offset, path, "%v", err)
+	}
+}
+
+func (d *decoder) errorf(offset int64, path []string, format string, args ...interface{}) error {
+	if offset > int64(len(d.data)) {
+		offset = int64(len(d.data))
+	}
+
+	before := d.data[:offset]
+
+	return &ConfigError{
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - bytes.LastIndexByte(before, '\n'),
+		Key:    strings.Join(path, "."),
+		Err:    fmt.Errorf(format, args...),
+	}
+}
@@ -0,0 +1,84 @@
+package wollemi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestUnmarshal(t *testing.T) {
+	for _, tt := range []struct {
+		Title string
+		Data  string
+		Want  wollemi.Config
+		Err   string
+	}{{
+		Title: "unmarshals valid config",
+		Data: `{
+  "$schema": "./wollemi.schema.json",
+  "default_visibility": "PUBLIC",
+  "gofmt": {"create": "off"}
+}`,
+		Want: wollemi.Config{
+			DefaultVisibility: "PUBLIC",
+			Gofmt: wollemi.Gofmt{
+				Create: []string{},
+			},
+		},
+	}, {
+		Title: "reports unknown top level key",
+		Data: `{
+  "default_visibility": "PUBLIC",
+  "explicit_source": true
+}`,
+		Err: "3:3: explicit_source: unknown key",
+	}, {
+		Title: "reports unknown nested key",
+		Data: `{
+  "gofmt": {
+    "rewrite": true,
+    "crate": ["go_library"]
+  }
+}`,
+		Err: "4:5: gofmt.crate: unknown key",
+	}, {
+		Title: "reports value of wrong type",
+		Data: `{
+  "known_dependency": {
+    "github.com/olivere/elastic": 7
+  }
+}`,
+		Err: "3:35: known_dependency.github.com/olivere/elastic: cannot use number as string",
+	}, {
+		Title: "reports invalid gofmt create value",
+		Data:  `{"gofmt": {"create": "sometimes"}}`,
+		Err:   `1:22: gofmt.create: expected list of rule kinds or one of "on", "default", "off"`,
+	}, {
+		Title: "reports invalid gofmt manage value",
+		Data:  `{"gofmt": {"manage": true}}`,
+		Err:   `1:22: gofmt.manage: expected list of rule kinds or one of "on", "default", "off"`,
+	}, {
+		Title: "reports syntax errors",
+		Data: `{
+  "default_visibility": "PUBLIC",
+}`,
+		Err: "3:1: invalid character '}' looking for beginning of object key string",
+	}} {
+		t.Run(tt.Title, func(t *testing.T) {
+			have := wollemi.Config{}
+
+			err := wollemi.Unmarshal([]byte(tt.Data), &have)
+
+			if tt.Err != "" {
+				require.EqualError(t, err, tt.Err)
+				require.IsType(t, &wollemi.ConfigError{}, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.Want, have)
+		})
+	}
+}
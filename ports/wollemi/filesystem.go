@@ -10,6 +10,7 @@ type Filesystem interface {
 	Stat(string) (os.FileInfo, error)
 	Lstat(string) (os.FileInfo, error)
 	Config(string) Config
+	ConfigFiles(string) []ConfigFile
 	Walk(string, filepath.WalkFunc) error
 	ReadAll(*bytes.Buffer, string) error
 	ReadDir(string) ([]os.FileInfo, error)
@@ -18,4 +19,5 @@ type Filesystem interface {
 	RemoveAll(string) error
 	Remove(string) error
 	MkdirAll(string, os.FileMode) error
+	WriteFile(string, []byte, os.FileMode) error
 }
@@ -0,0 +1,82 @@
+package wollemi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+	"github.com/tcncloud/wollemi/ports/please"
+)
+
+// PleaseConfig converts the [wollemi] section of a .plzconfig into a config.
+// The create and manage keys accept the same "on", "default" and "off" values
+// as a .wollemi.json whereas each mapped value is given as "from:into".
+func PleaseConfig(section please.Wollemi) (Config, error) {
+	var gofmt *Gofmt
+
+	config := Config{
+		DefaultVisibility: section.DefaultVisibility,
+		ThirdPartyDirs:    section.ThirdPartyDir,
+		Ignore:            section.Ignore,
+	}
+
+	if v := section.AllowUnresolvedDependency; v != nil {
+		config.AllowUnresolvedDependency = optional.BoolValue(*v)
+	}
+
+	if v := section.ExplicitSources; v != nil {
+		config.ExplicitSources = optional.BoolValue(*v)
+	}
+
+	if list := section.Create; list != nil {
+		config.Gofmt.Create = pleaseKinds(list, gofmt.GetCreate())
+	}
+
+	if list := section.Manage; list != nil {
+		var expand []string
+
+		for _, kind := range pleaseKinds(list, gofmt.GetManage()) {
+			if kind == "default" {
+				expand = appendUniqString(expand, gofmt.GetManage()...)
+			} else {
+				expand = appendUniqString(expand, kind)
+			}
+		}
+
+		config.Gofmt.Manage = append([]string{}, expand...)
+	}
+
+	if list := section.Mapped; list != nil {
+		config.Gofmt.Mapped = map[string]string{
+			"go_binary":  "go_binary",
+			"go_library": "go_library",
+			"go_test":    "go_test",
+		}
+
+		if len(list) != 1 || list[0] != "none" {
+			for _, value := range list {
+				chunks := strings.Split(value, ":")
+				if len(chunks) != 2 || chunks[0] == "" || chunks[1] == "" {
+					return Config{}, fmt.Errorf("mapped: expected from:into rule kinds, got %q", value)
+				}
+
+				config.Gofmt.Mapped[chunks[0]] = chunks[1]
+			}
+		}
+	}
+
+	return config, nil
+}
+
+func pleaseKinds(list []string, defaults []string) []string {
+	if len(list) == 1 {
+		switch list[0] {
+		case "on", "default":
+			return defaults
+		case "off":
+			return []string{}
+		}
+	}
+
+	return list
+}
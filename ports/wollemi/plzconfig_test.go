@@ -0,0 +1,104 @@
+package wollemi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+	"github.com/tcncloud/wollemi/ports/please"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestPleaseConfig(t *testing.T) {
+	for _, tt := range []struct {
+		Title   string
+		Section please.Wollemi
+		Want    wollemi.Config
+		Err     string
+	}{{
+		Title: "converts empty section into empty config",
+	}, {
+		Title: "converts wollemi section into config",
+		Section: please.Wollemi{
+			DefaultVisibility:         "//project/...",
+			AllowUnresolvedDependency: wollemi.Bool(true),
+			ExplicitSources:           wollemi.Bool(false),
+			Create:                    []string{"go_library", "go_test"},
+			Manage:                    []string{"default", "go_custom_binary"},
+			Mapped:                    []string{"go_test:go_custom_test"},
+			ThirdPartyDir:             []string{"third_party/go", "vendor/go"},
+			Ignore:                    []string{"experiments/", "!experiments/shared/"},
+		},
+		Want: wollemi.Config{
+			DefaultVisibility:         "//project/...",
+			AllowUnresolvedDependency: optional.BoolValue(true),
+			ExplicitSources:           optional.BoolValue(false),
+			ThirdPartyDirs:            []string{"third_party/go", "vendor/go"},
+			Ignore:                    []string{"experiments/", "!experiments/shared/"},
+			Gofmt: wollemi.Gofmt{
+				Create: []string{"go_library", "go_test"},
+				Manage: []string{"go_binary", "go_library", "go_test", "go_custom_binary"},
+				Mapped: map[string]string{
+					"go_binary":  "go_binary",
+					"go_library": "go_library",
+					"go_test":    "go_custom_test",
+				},
+			},
+		},
+	}, {
+		Title: "converts create and manage set to off",
+		Section: please.Wollemi{
+			Create: []string{"off"},
+			Manage: []string{"off"},
+		},
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Create: []string{},
+				Manage: []string{},
+			},
+		},
+	}, {
+		Title: "converts create set to on",
+		Section: please.Wollemi{
+			Create: []string{"on"},
+		},
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Create: []string{"go_binary", "go_library", "go_test"},
+			},
+		},
+	}, {
+		Title: "converts mapped set to none",
+		Section: please.Wollemi{
+			Mapped: []string{"none"},
+		},
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Mapped: map[string]string{
+					"go_binary":  "go_binary",
+					"go_library": "go_library",
+					"go_test":    "go_test",
+				},
+			},
+		},
+	}, {
+		Title: "returns error when mapped value is malformed",
+		Section: please.Wollemi{
+			Mapped: []string{"go_test"},
+		},
+		Err: `mapped: expected from:into rule kinds, got "go_test"`,
+	}} {
+		t.Run(tt.Title, func(t *testing.T) {
+			have, err := wollemi.PleaseConfig(tt.Section)
+
+			if tt.Err != "" {
+				require.EqualError(t, err, tt.Err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.Want, have)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package wollemi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+)
+
+// ConfigFile is a config together with the path of the file it was read from.
+type ConfigFile struct {
+	Path   string
+	Config Config
+
+	// Dir is the directory of the .wollemi.json file which declared or extended
+	// the config, to which its ignore patterns are relative. It is empty for
+	// the base config which, like the project root, applies everywhere.
+	Dir string
+}
+
+// ConfigProvenance is the json representation of a merged config where every
+// field is annotated with the source which set it.
+type ConfigProvenance map[string]interface{}
+
+// DefaultConfig returns the config values which apply when no config file
+// overrides them.
+func DefaultConfig() Config {
+	var gofmt *Gofmt
+
+	mapped := make(map[string]string)
+	for _, kind := range gofmt.GetCreate() {
+		mapped[kind] = gofmt.GetMapped(kind)
+	}
+
+	var unused *Unused
+
+	subTargets := make(map[string][]string, len(defaultUnusedSubTargets))
+	for kind, names := range defaultUnusedSubTargets {
+		subTargets[kind] = names
+	}
+
+	return Config{
+		Unused: Unused{
+			Kinds:      unused.GetKinds(),
+			SubTargets: subTargets,
+		},
+		AllowUnresolvedDependency: optional.BoolValue(false),
+		ExplicitSources:           optional.BoolValue(false),
+		ThirdPartyDirs:            Config{}.GetThirdPartyDirs(),
+		Gofmt: Gofmt{
+			Rewrite: Bool(gofmt.GetRewrite()),
+			Create:  gofmt.GetCreate(),
+			Manage:  gofmt.GetManage(),
+			Mapped:  mapped,
+			Naming: Naming{
+				GoBinary:     gofmt.GetName("go_binary", "{dir}", "{pkg}"),
+				GoLibrary:    gofmt.GetName("go_library", "{dir}", "{pkg}"),
+				GoTest:       gofmt.GetName("go_test", "{dir}", "{pkg}"),
+				InternalTest: gofmt.GetName("internal_test", "{dir}", "{pkg}"),
+				ExternalTest: gofmt.GetName("external_test", "{dir}", "{pkg}"),
+			},
+			WidenVisibility: Bool(gofmt.GetWidenVisibility()),
+			InferTestOnly:   Bool(gofmt.GetInferTestOnly()),
+			InstallMissing:  Bool(gofmt.GetInstallMissing()),
+			FixUnresolved:   Bool(gofmt.GetFixUnresolved()),
+		},
+	}
+}
+
+// Provenance merges the provided config files in order and annotates every
+// field of the result with the path of the last file which set it.
+func Provenance(files ...ConfigFile) ConfigProvenance {
+	var merge Config
+
+	sources := make(map[string]string)
+
+	for _, file := range files {
+		merge = merge.Merge(file.Config)
+
+		have := flattenConfig(merge)
+
+		for key := range sources {
+			if _, ok := have[key]; !ok {
+				delete(sources, key)
+			}
+		}
+
+		for key := range flattenConfig(file.Config) {
+			if _, ok := have[key]; ok {
+				sources[key] = file.Path
+			}
+		}
+	}
+
+	out := make(ConfigProvenance)
+
+	keys := make([]string, 0, len(sources))
+	for key := range sources {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	have := flattenConfig(merge)
+
+	for _, key := range keys {
+		path := strings.Split(key, keySep)
+		node := map[string]interface{}(out)
+
+		for _, name := range path[:len(path)-1] {
+			next, ok := node[name].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[name] = next
+			}
+
+			node = next
+		}
+
+		node[path[len(path)-1]] = map[string]interface{}{
+			"value":  have[key],
+			"source": sources[key],
+		}
+	}
+
+	return out
+}
+
+// keySep separates the json object keys of a flattened config. Object keys
+// such as go import paths may contain almost anything so a control character
+// is used instead of a dot.
+const keySep = "\x00"
+
+// flattenConfig returns every json leaf value of the config keyed by the
+// path of object keys leading to it.
+func flattenConfig(config Config) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return out
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(buf, &tree); err != nil {
+		return out
+	}
+
+	var flatten func(string, interface{})
+
+	flatten = func(prefix string, value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, value := range value {
+				if prefix != "" {
+					key = prefix + keySep + key
+				}
+
+				flatten(key, value)
+			}
+		case nil:
+		default:
+			out[prefix] = value
+		}
+	}
+
+	flatten("", tree)
+
+	return out
+}
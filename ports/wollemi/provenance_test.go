@@ -0,0 +1,147 @@
+package wollemi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestProvenance(t *testing.T) {
+	for _, tt := range []struct {
+		Name  string
+		Files []wollemi.ConfigFile
+		Want  string
+	}{{
+		Name: "annotates defaults when no config files override them",
+		Files: []wollemi.ConfigFile{{
+			Path:   "default",
+			Config: wollemi.DefaultConfig(),
+		}},
+		Want: `{
+			"allow_unresolved_dependency": {"source": "default", "value": false},
+			"explicit_sources": {"source": "default", "value": false},
+			"gofmt": {
+				"create": {"source": "default", "value": ["go_binary", "go_library", "go_test"]},
+				"fix_unresolved": {"source": "default", "value": false},
+				"infer_test_only": {"source": "default", "value": false},
+				"install_missing": {"source": "default", "value": false},
+				"manage": {"source": "default", "value": ["go_binary", "go_library", "go_test"]},
+				"mapped": {
+					"go_binary": {"source": "default", "value": "go_binary"},
+					"go_library": {"source": "default", "value": "go_library"},
+					"go_test": {"source": "default", "value": "go_test"}
+				},
+				"naming": {
+					"external_test": {"source": "default", "value": "external_test"},
+					"go_binary": {"source": "default", "value": "{dir}"},
+					"go_library": {"source": "default", "value": "{dir}"},
+					"go_test": {"source": "default", "value": "test"},
+					"internal_test": {"source": "default", "value": "internal_test"}
+				},
+				"rewrite": {"source": "default", "value": true},
+				"widen_visibility": {"source": "default", "value": false}
+			},
+			"third_party_dirs": {"source": "default", "value": ["third_party/go"]},
+			"unused": {
+				"kinds": {"source": "default", "value": [
+					"export_file",
+					"filegroup",
+					"genrule",
+					"go_get",
+					"go_get_with_sources",
+					"go_library",
+					"go_mock",
+					"go_module",
+					"grpc_library",
+					"pip_library"
+				]},
+				"sub_targets": {
+					"grpc_library": {"source": "default", "value": [
+						"_{name}#proto",
+						"_{name}#go",
+						"_{name}#java",
+						"_{name}#py",
+						"_{name}#ts"
+					]},
+					"pip_library": {"source": "default", "value": ["_{name}#wheel"]}
+				}
+			}
+		}`,
+	}, {
+		Name: "annotates each field with the last file which set it",
+		Files: []wollemi.ConfigFile{{
+			Path: ".wollemi.json",
+			Config: wollemi.Config{
+				DefaultVisibility: "PUBLIC",
+				ExplicitSources:   optional.BoolValue(true),
+				KnownDependency: map[string]string{
+					"github.com/olivere/elastic": "//third_party/go/github.com/olivere/elastic:v6",
+					"go.opencensus.io":           "//third_party/go/go.opencensus.io:all_libs",
+				},
+			},
+		}, {
+			Path: "app/.wollemi.json",
+			Config: wollemi.Config{
+				DefaultVisibility: "//app/...",
+				KnownDependency: map[string]string{
+					"github.com/olivere/elastic": "//third_party/go/github.com/olivere/elastic:v7",
+				},
+				Gofmt: wollemi.Gofmt{
+					Create: []string{},
+				},
+			},
+		}},
+		Want: `{
+			"default_visibility": {"source": "app/.wollemi.json", "value": "//app/..."},
+			"explicit_sources": {"source": ".wollemi.json", "value": true},
+			"gofmt": {
+				"create": {"source": "app/.wollemi.json", "value": []}
+			},
+			"known_dependency": {
+				"github.com/olivere/elastic": {
+					"source": "app/.wollemi.json",
+					"value": "//third_party/go/github.com/olivere/elastic:v7"
+				},
+				"go.opencensus.io": {
+					"source": ".wollemi.json",
+					"value": "//third_party/go/go.opencensus.io:all_libs"
+				}
+			}
+		}`,
+	}, {
+		Name: "drops sources of map entries replaced by a later file",
+		Files: []wollemi.ConfigFile{{
+			Path: ".wollemi.json",
+			Config: wollemi.Config{
+				Gofmt: wollemi.Gofmt{
+					Mapped: map[string]string{"go_test": "go_custom_test"},
+				},
+			},
+		}, {
+			Path: "app/.wollemi.json",
+			Config: wollemi.Config{
+				Gofmt: wollemi.Gofmt{
+					Mapped: map[string]string{"go_binary": "go_custom_binary"},
+				},
+			},
+		}},
+		Want: `{
+			"gofmt": {
+				"mapped": {
+					"go_binary": {"source": "app/.wollemi.json", "value": "go_custom_binary"}
+				}
+			}
+		}`,
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			buf, err := json.Marshal(wollemi.Provenance(tt.Files...))
+			require.NoError(t, err)
+
+			require.JSONEq(t, tt.Want, string(buf))
+		})
+	}
+}
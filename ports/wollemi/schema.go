@@ -0,0 +1,100 @@
+package wollemi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema returns the JSON Schema of a .wollemi.json config file generated from
+// the Config type.
+func Schema() map[string]interface{} {
+	schema := schemaOf(reflect.TypeOf(Config{}))
+
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "wollemi config"
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, key := range ignoredKeys {
+		properties[key] = map[string]interface{}{"type": "string"}
+	}
+
+	return schema
+}
+
+// schemaer is implemented by config types which accept more than their go type
+// when unmarshaled from json.
+type schemaer interface {
+	schema() map[string]interface{}
+}
+
+var schemaerType = reflect.TypeOf((*schemaer)(nil)).Elem()
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	if t.Implements(schemaerType) {
+		return reflect.Zero(t).Interface().(schemaer).schema()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{}, t.NumField())
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+			if name == "" || name == "-" {
+				continue
+			}
+
+			properties[name] = schemaOf(field.Type)
+		}
+
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem()),
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaOf(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (gofmtCreate) schema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			schemaOf(reflect.TypeOf([]string(nil))),
+			map[string]interface{}{"enum": []string{"on", "default", "off"}},
+		},
+	}
+}
+
+func (gofmtManage) schema() map[string]interface{} {
+	return gofmtCreate(nil).schema()
+}
+
+func (gofmtMapped) schema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			schemaOf(reflect.TypeOf(map[string]string(nil))),
+			map[string]interface{}{"enum": []string{"none"}},
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package wollemi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestSchema(t *testing.T) {
+	schema := wollemi.Schema()
+
+	t.Run("disallows unknown keys", func(t *testing.T) {
+		require.Equal(t, false, schema["additionalProperties"])
+	})
+
+	t.Run("describes every config key", func(t *testing.T) {
+		properties := schema["properties"].(map[string]interface{})
+
+		for _, key := range []string{
+			"$schema",
+			"allow_unresolved_dependency",
+			"default_visibility",
+			"explicit_sources",
+			"gofmt",
+			"known_dependency",
+		} {
+			require.Contains(t, properties, key)
+		}
+
+		gofmt := properties["gofmt"].(map[string]interface{})
+
+		require.Equal(t, map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+				map[string]interface{}{
+					"enum": []string{"on", "default", "off"},
+				},
+			},
+		}, gofmt["properties"].(map[string]interface{})["create"])
+	})
+}
@@ -0,0 +1,279 @@
+package wollemi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables which override config keys.
+const EnvPrefix = "WOLLEMI_"
+
+// fixedKeys are the config keys which are resolved when a config file is read,
+// such as the config files it extends, so they cannot be overridden.
+var fixedKeys = []string{"extends"}
+
+// Set overrides the config value at the dotted key path. Boolean and string
+// values are given as is, lists are comma separated and maps are comma
+// separated key=value pairs. A single map entry can be set by appending the
+// map key to the path, e.g. known_dependency.github.com/spf13/cobra=//third_party/go:cobra.
+// The values of maps of lists are comma separated when setting a single entry
+// whereas a key is repeated to give several values when setting the whole map.
+// Overrides are merged over the config files so an empty string or list leaves
+// the value of the config files in place rather than clearing it.
+func (config *Config) Set(key, value string) error {
+	for _, fixed := range fixedKeys {
+		if key == fixed || strings.HasPrefix(key, fixed+".") {
+			return fmt.Errorf("%s: cannot be overridden", key)
+		}
+	}
+
+	if err := set(reflect.ValueOf(config).Elem(), key, key, value); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+
+	return nil
+}
+
+// EnvKey converts the name of a WOLLEMI_ prefixed environment variable into the
+// dotted config key path it overrides, e.g. WOLLEMI_GOFMT_REWRITE is converted
+// into gofmt.rewrite. Map entries cannot be addressed by environment variables
+// so the variable of a map gives all of its overriding entries, which are
+// merged over the entries of the config files like any other config.
+func EnvKey(name string) (string, bool) {
+	if !strings.HasPrefix(name, EnvPrefix) {
+		return "", false
+	}
+
+	name = strings.ToLower(strings.TrimPrefix(name, EnvPrefix))
+
+	var path []string
+
+	for t := reflect.TypeOf(Config{}); name != ""; {
+		if t.Kind() != reflect.Struct {
+			return "", false
+		}
+
+		field, ok := "", false
+
+		for i := 0; i < t.NumField(); i++ {
+			key := jsonKey(t.Field(i))
+
+			if key == name || strings.HasPrefix(name, key+"_") {
+				field, ok = key, true
+				name = strings.TrimPrefix(strings.TrimPrefix(name, key), "_")
+				t = t.Field(i).Type
+				break
+			}
+		}
+
+		if !ok {
+			return "", false
+		}
+
+		path = append(path, field)
+
+		if name != "" && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	return strings.Join(path, "."), true
+}
+
+func set(v reflect.Value, path, key, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		name := strings.SplitN(path, ".", 2)
+
+		for i := 0; i < v.NumField(); i++ {
+			if jsonKey(v.Type().Field(i)) != name[0] {
+				continue
+			}
+
+			if len(name) == 1 {
+				return setValue(v.Field(i), value)
+			}
+
+			field := v.Field(i)
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+
+				field = field.Elem()
+			}
+
+			return set(field, name[1], key, value)
+		}
+
+		return fmt.Errorf("unknown key")
+	case reflect.Map:
+		if err := initMap(v); err != nil {
+			return err
+		}
+
+		if v.Type().Elem().Kind() == reflect.Map {
+			name := strings.SplitN(path, ".", 2)
+			if len(name) == 1 {
+				return fmt.Errorf("unknown key")
+			}
+
+			key := reflect.ValueOf(name[0]).Convert(v.Type().Key())
+
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if have := v.MapIndex(key); have.IsValid() {
+				elem.Set(have)
+			}
+
+			if err := set(elem, name[1], key.String(), value); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(key, elem)
+
+			return nil
+		}
+
+		// Map keys such as go import paths contain dots so the remainder of the
+		// path is the map key.
+		elem, err := mapValue(v, value)
+		if err != nil {
+			return err
+		}
+
+		v.SetMapIndex(reflect.ValueOf(path).Convert(v.Type().Key()), elem)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown key")
+	}
+}
+
+func setValue(v reflect.Value, value string) error {
+	if unmarshaler, ok := v.Addr().Interface().(json.Unmarshaler); ok {
+		// Special values such as "off" or "none" take precedence over lists.
+		if err := unmarshaler.UnmarshalJSON([]byte(strconv.Quote(value))); err == nil {
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected boolean, got %q", value)
+		}
+
+		v.SetBool(b)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Slice:
+		list := []string{}
+
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+
+		buf, _ := json.Marshal(list)
+
+		return json.Unmarshal(buf, v.Addr().Interface())
+	case reflect.Map:
+		if v.Type().Elem().Kind() == reflect.Map {
+			return fmt.Errorf("expected nested key")
+		}
+
+		v.Set(reflect.Zero(v.Type()))
+
+		if err := initMap(v); err != nil {
+			return err
+		}
+
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("expected key=value pairs, got %q", pair)
+			}
+
+			elem, err := mapValue(v, kv[1])
+			if err != nil {
+				return err
+			}
+
+			key := reflect.ValueOf(kv[0]).Convert(v.Type().Key())
+
+			// The values of list entries given by repeated pairs are appended.
+			if have := v.MapIndex(key); have.IsValid() && elem.Kind() == reflect.Slice {
+				elem = reflect.AppendSlice(have, elem)
+			}
+
+			v.SetMapIndex(key, elem)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// mapValue converts the value into the element type of the map. Values of maps
+// with untyped elements are decoded as json when possible and values of maps
+// with list elements are comma separated.
+func mapValue(m reflect.Value, value string) (reflect.Value, error) {
+	switch m.Type().Elem().Kind() {
+	case reflect.Interface:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return reflect.ValueOf(&v).Elem(), nil
+		}
+	case reflect.Slice:
+		elem := reflect.New(m.Type().Elem()).Elem()
+
+		if err := setValue(elem, value); err != nil {
+			return reflect.Value{}, err
+		}
+
+		return elem, nil
+	case reflect.String:
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", m.Type().Elem())
+	}
+
+	return reflect.ValueOf(value).Convert(m.Type().Elem()), nil
+}
+
+// initMap allocates a nil map. Maps which unmarshal from json are allocated by
+// unmarshaling an empty object so that they receive their default entries.
+func initMap(v reflect.Value) error {
+	if !v.IsNil() {
+		return nil
+	}
+
+	if unmarshaler, ok := v.Addr().Interface().(json.Unmarshaler); ok {
+		return unmarshaler.UnmarshalJSON([]byte("{}"))
+	}
+
+	v.Set(reflect.MakeMap(v.Type()))
+
+	return nil
+}
+
+func jsonKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
@@ -0,0 +1,221 @@
+package wollemi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tcncloud/wollemi/domain/optional"
+	"github.com/tcncloud/wollemi/ports/wollemi"
+)
+
+func TestConfig_Set(t *testing.T) {
+	for _, tt := range []struct {
+		Title string
+		Key   string
+		Value string
+		Want  wollemi.Config
+		Err   string
+	}{{
+		Title: "sets string value",
+		Key:   "default_visibility",
+		Value: "//app/...",
+		Want: wollemi.Config{
+			DefaultVisibility: "//app/...",
+		},
+	}, {
+		Title: "sets optional boolean value",
+		Key:   "explicit_sources",
+		Value: "true",
+		Want: wollemi.Config{
+			ExplicitSources: optional.BoolValue(true),
+		},
+	}, {
+		Title: "sets nested boolean value",
+		Key:   "gofmt.rewrite",
+		Value: "false",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Rewrite: wollemi.Bool(false),
+			},
+		},
+	}, {
+		Title: "sets comma separated list value",
+		Key:   "gofmt.create",
+		Value: "go_library,go_test",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Create: []string{"go_library", "go_test"},
+			},
+		},
+	}, {
+		Title: "sets special list value",
+		Key:   "gofmt.manage",
+		Value: "off",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Manage: []string{},
+			},
+		},
+	}, {
+		Title: "expands default in list value",
+		Key:   "gofmt.manage",
+		Value: "default,go_custom_binary",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Manage: []string{"go_binary", "go_library", "go_test", "go_custom_binary"},
+			},
+		},
+	}, {
+		Title: "sets map entry using remainder of key path",
+		Key:   "known_dependency.github.com/olivere/elastic",
+		Value: "//third_party/go/github.com/olivere/elastic:v7",
+		Want: wollemi.Config{
+			KnownDependency: map[string]string{
+				"github.com/olivere/elastic": "//third_party/go/github.com/olivere/elastic:v7",
+			},
+		},
+	}, {
+		Title: "sets whole map from key value pairs",
+		Key:   "gofmt.mapped",
+		Value: "go_test=go_custom_test",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Mapped: map[string]string{
+					"go_binary":  "go_binary",
+					"go_library": "go_library",
+					"go_test":    "go_custom_test",
+				},
+			},
+		},
+	}, {
+		Title: "sets single mapped entry on top of mapped defaults",
+		Key:   "gofmt.mapped.go_binary",
+		Value: "go_custom_binary",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Mapped: map[string]string{
+					"go_binary":  "go_custom_binary",
+					"go_library": "go_library",
+					"go_test":    "go_test",
+				},
+			},
+		},
+	}, {
+		Title: "sets rule template attribute decoding json values",
+		Key:   "gofmt.template.go_test.labels",
+		Value: `["integration"]`,
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Template: map[string]map[string]interface{}{
+					"go_test": {"labels": []interface{}{"integration"}},
+				},
+			},
+		},
+	}, {
+		Title: "sets rule template attribute as string when not json",
+		Key:   "gofmt.template.go_test.size",
+		Value: "large",
+		Want: wollemi.Config{
+			Gofmt: wollemi.Gofmt{
+				Template: map[string]map[string]interface{}{
+					"go_test": {"size": "large"},
+				},
+			},
+		},
+	}, {
+		Title: "sets map of lists entry",
+		Key:   "unused.sub_targets.grpc_library",
+		Value: "_%s#go, _%s#py",
+		Want: wollemi.Config{
+			Unused: wollemi.Unused{
+				SubTargets: map[string][]string{
+					"grpc_library": {"_%s#go", "_%s#py"},
+				},
+			},
+		},
+	}, {
+		Title: "sets map of lists from repeated pairs",
+		Key:   "unused.sub_targets",
+		Value: "grpc_library=_%s#go,grpc_library=_%s#py,go_module=_%s#download",
+		Want: wollemi.Config{
+			Unused: wollemi.Unused{
+				SubTargets: map[string][]string{
+					"grpc_library": {"_%s#go", "_%s#py"},
+					"go_module":    {"_%s#download"},
+				},
+			},
+		},
+	}, {
+		Title: "returns error for unknown key",
+		Key:   "gofmt.crate",
+		Value: "off",
+		Err:   "gofmt.crate: unknown key",
+	}, {
+		Title: "returns error for keys which cannot be overridden",
+		Key:   "extends",
+		Value: "shared/base.json",
+		Err:   "extends: cannot be overridden",
+	}, {
+		Title: "returns error for invalid boolean",
+		Key:   "allow_unresolved_dependency",
+		Value: "sometimes",
+		Err:   `allow_unresolved_dependency: expected boolean, got "sometimes"`,
+	}, {
+		Title: "returns error for invalid map pair",
+		Key:   "known_dependency",
+		Value: "github.com/olivere/elastic",
+		Err:   `known_dependency: expected key=value pairs, got "github.com/olivere/elastic"`,
+	}} {
+		t.Run(tt.Title, func(t *testing.T) {
+			have := wollemi.Config{}
+
+			err := have.Set(tt.Key, tt.Value)
+
+			if tt.Err != "" {
+				require.EqualError(t, err, tt.Err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.Want, have)
+		})
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	for _, tt := range []struct {
+		Name string
+		Want string
+		Ok   bool
+	}{{
+		Name: "WOLLEMI_DEFAULT_VISIBILITY",
+		Want: "default_visibility",
+		Ok:   true,
+	}, {
+		Name: "WOLLEMI_GOFMT_REWRITE",
+		Want: "gofmt.rewrite",
+		Ok:   true,
+	}, {
+		Name: "WOLLEMI_KNOWN_DEPENDENCY",
+		Want: "known_dependency",
+		Ok:   true,
+	}, {
+		Name: "WOLLEMI_UNUSED_SUB_TARGETS",
+		Want: "unused.sub_targets",
+		Ok:   true,
+	}, {
+		Name: "WOLLEMI_GOFMT_CRATE",
+	}, {
+		Name: "WOLLEMI_EXPLICIT_SOURCES_FOO",
+	}, {
+		Name: "GOFMT_REWRITE",
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, ok := wollemi.EnvKey(tt.Name)
+
+			require.Equal(t, tt.Ok, ok)
+			require.Equal(t, tt.Want, have)
+		})
+	}
+}
@@ -21,6 +21,7 @@ type (
 	Graph        = please.Graph
 	GraphTarget  = please.GraphTarget
 	GraphPackage = please.GraphPackage
+	Config       = please.Config
 )
 
 type BuildFile struct {
@@ -125,6 +126,27 @@ func (this *BuildFile) SetRule(rule please.Rule) {
 	}
 }
 
+func (this *BuildFile) AddRule(rule please.Rule) {
+	switch rule := rule.(type) {
+	case *Rule:
+		this.Stmt = append(this.Stmt, rule.Call)
+	default:
+		panic(fmt.Errorf("rule not created by package"))
+	}
+}
+
+func (this *BuildFile) NumOtherStmts() int {
+	var n int
+
+	for _, stmt := range this.Stmt {
+		if _, ok := stmt.(*please.CallExpr); !ok {
+			n++
+		}
+	}
+
+	return n
+}
+
 func NewRule(kind, name string) *Rule {
 	return &Rule{
 		Call: &please.CallExpr{